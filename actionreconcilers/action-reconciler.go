@@ -7,6 +7,7 @@ import (
 
 	"github.com/byuoitav/av-api/base"
 	ce "github.com/byuoitav/av-api/commandevaluators"
+	"github.com/byuoitav/av-api/registry"
 	"github.com/byuoitav/common/log"
 	"github.com/fatih/color"
 )
@@ -30,21 +31,37 @@ type ActionReconciler interface {
 	Reconcile([]base.ActionStructure, int) ([]base.ActionStructure, int, error)
 }
 
-//reconcilerMap is a singleton that maps known keys to their reconciler struct.
+//FLAG marks the evaluator in a room's configuration that names its reconciler, e.g. RECONCILER_StrictPriority.
+const FLAG = "RECONCILER_"
+
+//reconcilerMap is a singleton that maps known keys to their reconciler struct, filled in by Register.
 var reconcilerMap = make(map[string]ActionReconciler)
-var reconcilerMapInitialized = false
 
-//Init adds the reconcilers to the reconcilerMap.
-func Init() map[string]ActionReconciler {
-	if !reconcilerMapInitialized {
-		//-------------------------------
-		//Add reconcilers to the map here
-		//-------------------------------
-		reconcilerMap["Default"] = &DefaultReconciler{}
+//Register adds a reconciler under key. The registry reports a key that is registered twice.
+func Register(key string, reconciler ActionReconciler) {
+	registry.Register(registry.Entry{
+		Key:   key,
+		Kind:  registry.Reconciler,
+		Value: reconciler,
+	})
 
-		reconcilerMapInitialized = true
+	if _, ok := reconcilerMap[key]; !ok {
+		reconcilerMap[key] = reconciler
 	}
+}
 
+func init() {
+	//-------------------------------
+	//Add reconcilers to the map here
+	//-------------------------------
+	Register("Default", &DefaultReconciler{})
+	Register("StrictPriority", &StrictPriorityReconciler{})
+	Register("Parallel", &ParallelReconciler{})
+	Register("RoleBased", &RoleBasedReconciler{})
+}
+
+//Init returns the reconcilerMap.
+func Init() map[string]ActionReconciler {
 	return reconcilerMap
 }
 
@@ -63,7 +80,7 @@ func StandardReconcile(device string, inCount int, actions []base.ActionStructur
 		actionsForEvaluation[action.Action] = action
 		//for each device, construct set of incompatible actions
 		//Value is the action that generated the incompatible action.
-		evaluator := ce.EVALUATORS[action.GeneratingEvaluator]
+		evaluator := ce.Lookup(action.GeneratingEvaluator)
 
 		if evaluator == nil {
 			color.Set(color.FgHiRed)
@@ -0,0 +1,159 @@
+package actionreconcilers
+
+import (
+	"strings"
+
+	"github.com/byuoitav/av-api/base"
+	ce "github.com/byuoitav/av-api/commandevaluators"
+	"github.com/byuoitav/av-api/helpers"
+	"github.com/byuoitav/common/log"
+	"github.com/fatih/color"
+)
+
+//ConflictFlag marks the evaluator in a room's configuration that names its conflict policy, e.g. CONFLICT_DropBoth.
+const ConflictFlag = "CONFLICT_"
+
+// The policies for resolving a request that asks for incompatible actions on the same device.
+// In every policy but DropBoth, an action asked for on the device itself beats one asked for room-wide.
+const (
+	//ConflictReject fails the whole request if the actions are equally specific.
+	ConflictReject = "Reject"
+	//ConflictLastEvaluatorWins keeps whichever action was generated last, i.e. by the later evaluator in the configuration.
+	//The order of the fields in the request doesn't matter.
+	ConflictLastEvaluatorWins = "LastEvaluatorWins"
+	//ConflictPrecedence keeps whichever action comes first in the room's precedence order, and rejects the request if neither is listed.
+	ConflictPrecedence = "Precedence"
+	//ConflictDropBoth drops both actions, and carries on with the rest of the request.
+	ConflictDropBoth = "DropBoth"
+)
+
+//DefaultPrecedence is the precedence order used if the CONFLICT_Precedence evaluator doesn't have one in its description.
+var DefaultPrecedence = []string{"Standby", "BlankDisplay", "Mute"}
+
+//ParsePrecedence reads the comma-separated commands in a CONFLICT_Precedence evaluator's description, or returns DefaultPrecedence.
+func ParsePrecedence(description string) []string {
+	var output []string
+	for _, command := range strings.Split(description, ",") {
+		if command = strings.TrimSpace(command); len(command) > 0 {
+			output = append(output, command)
+		}
+	}
+
+	if len(output) == 0 {
+		return DefaultPrecedence
+	}
+
+	return output
+}
+
+//ResolveConflicts finds the incompatible actions on each device, and drops the losers according to policy.
+//precedence is only used by ConflictPrecedence. It returns the actions that are left, and how each conflict was resolved.
+func ResolveConflicts(actions []base.ActionStructure, policy string, precedence []string) ([]base.ActionStructure, []base.Conflict, error) {
+	switch policy {
+	case ConflictReject, ConflictLastEvaluatorWins, ConflictPrecedence, ConflictDropBoth:
+	default:
+		return []base.ActionStructure{}, nil, helpers.NewError(helpers.ConfigError, "unknown conflict policy: %s", policy).WithDetail("policy", policy)
+	}
+
+	dropped := make([]bool, len(actions))
+	var conflicts []base.Conflict
+
+	for i := range actions {
+		for j := i + 1; j < len(actions); j++ {
+			if dropped[i] || dropped[j] || actions[i].Device.ID != actions[j].Device.ID || !incompatible(actions[i], actions[j]) {
+				continue
+			}
+
+			conflict := base.Conflict{
+				Device:  actions[i].Device.Name,
+				Actions: []string{actions[i].Action, actions[j].Action},
+				Policy:  policy,
+			}
+
+			winner := -1
+			switch {
+			case policy == ConflictDropBoth:
+				dropped[i], dropped[j] = true, true
+				log.L.Warnf("%s", color.HiYellowString("[reconciler] %s and %s are incompatible on %s, dropping both", actions[i].Action, actions[j].Action, actions[i].Device.Name))
+				conflicts = append(conflicts, conflict)
+				continue
+
+			case actions[i].DeviceSpecific && !actions[j].DeviceSpecific:
+				winner = i
+			case actions[j].DeviceSpecific && !actions[i].DeviceSpecific:
+				winner = j
+			case policy == ConflictLastEvaluatorWins:
+				winner = j
+			case policy == ConflictPrecedence:
+				winner = precede(precedence, actions, i, j)
+			}
+
+			if winner == -1 {
+				err := helpers.NewError(helpers.InvalidRequest, "%s is an incompatible action with %s for device with ID: %s", actions[j].Action, actions[i].Action, actions[i].Device.ID).
+					WithDetail("device", actions[i].Device.Name).
+					WithDetail("actions", conflict.Actions)
+				log.L.Errorf("%s", err.Error())
+				return []base.ActionStructure{}, nil, err
+			}
+
+			loser := i
+			if winner == i {
+				loser = j
+			}
+
+			dropped[loser] = true
+			conflict.Kept = actions[winner].Action
+
+			log.L.Infof("[reconciler] %s and %s are incompatible on %s, keeping %s", actions[i].Action, actions[j].Action, actions[i].Device.Name, conflict.Kept)
+			conflicts = append(conflicts, conflict)
+		}
+	}
+
+	var output []base.ActionStructure
+	for i := range actions {
+		if !dropped[i] {
+			output = append(output, actions[i])
+		}
+	}
+
+	return output, conflicts, nil
+}
+
+//incompatible reports whether either action's evaluator says it can't be run with the other.
+func incompatible(a, b base.ActionStructure) bool {
+	return listsAction(a, b.Action) || listsAction(b, a.Action)
+}
+
+func listsAction(action base.ActionStructure, other string) bool {
+	evaluator := ce.Lookup(action.GeneratingEvaluator)
+	if evaluator == nil {
+		return false
+	}
+
+	for _, command := range evaluator.GetIncompatibleCommands() {
+		if strings.EqualFold(command, other) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//precede returns whichever of the two actions comes first in the precedence order, or -1 if neither is in it.
+func precede(precedence []string, actions []base.ActionStructure, i, j int) int {
+	for _, command := range precedence {
+		iMatches := strings.EqualFold(command, actions[i].Action)
+		jMatches := strings.EqualFold(command, actions[j].Action)
+
+		switch {
+		case iMatches && jMatches:
+			return -1
+		case iMatches:
+			return i
+		case jMatches:
+			return j
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,147 @@
+package actionreconcilers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/av-api/helpers"
+)
+
+func power(name, action string, deviceSpecific bool) base.ActionStructure {
+	return base.ActionStructure{
+		Action:              action,
+		GeneratingEvaluator: action + "Default",
+		Device:              device(name, "VideoOut"),
+		DeviceSpecific:      deviceSpecific,
+	}
+}
+
+func TestResolveConflicts(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     string
+		precedence string
+		actions    []base.ActionStructure
+		kept       []string
+		conflicts  []base.Conflict
+		fails      helpers.Code
+	}{
+		{
+			name:    "device-specific beats room-wide",
+			policy:  ConflictReject,
+			actions: []base.ActionStructure{power("D1", "PowerOn", false), power("D1", "Standby", true)},
+			kept:    []string{"D1 Standby"},
+			conflicts: []base.Conflict{
+				{Device: "D1", Actions: []string{"PowerOn", "Standby"}, Kept: "Standby", Policy: ConflictReject},
+			},
+		},
+		{
+			name:    "reject equally specific",
+			policy:  ConflictReject,
+			actions: []base.ActionStructure{power("D1", "PowerOn", true), power("D1", "Standby", true)},
+			fails:   helpers.InvalidRequest,
+		},
+		{
+			name:    "other devices aren't in conflict",
+			policy:  ConflictReject,
+			actions: []base.ActionStructure{power("D1", "PowerOn", true), power("D2", "Standby", true)},
+			kept:    []string{"D1 PowerOn", "D2 Standby"},
+		},
+		{
+			name:    "last evaluator wins",
+			policy:  ConflictLastEvaluatorWins,
+			actions: []base.ActionStructure{power("D1", "Standby", false), power("D1", "PowerOn", false)},
+			kept:    []string{"D1 PowerOn"},
+			conflicts: []base.Conflict{
+				{Device: "D1", Actions: []string{"Standby", "PowerOn"}, Kept: "PowerOn", Policy: ConflictLastEvaluatorWins},
+			},
+		},
+		{
+			name:    "last evaluator still loses to device-specific",
+			policy:  ConflictLastEvaluatorWins,
+			actions: []base.ActionStructure{power("D1", "Standby", true), power("D1", "PowerOn", false)},
+			kept:    []string{"D1 Standby"},
+			conflicts: []base.Conflict{
+				{Device: "D1", Actions: []string{"Standby", "PowerOn"}, Kept: "Standby", Policy: ConflictLastEvaluatorWins},
+			},
+		},
+		{
+			name:    "default precedence",
+			policy:  ConflictPrecedence,
+			actions: []base.ActionStructure{power("D1", "PowerOn", false), power("D1", "Standby", false)},
+			kept:    []string{"D1 Standby"},
+			conflicts: []base.Conflict{
+				{Device: "D1", Actions: []string{"PowerOn", "Standby"}, Kept: "Standby", Policy: ConflictPrecedence},
+			},
+		},
+		{
+			name:       "configured precedence",
+			policy:     ConflictPrecedence,
+			precedence: "PowerOn, Standby",
+			actions:    []base.ActionStructure{power("D1", "Standby", false), power("D1", "PowerOn", false)},
+			kept:       []string{"D1 PowerOn"},
+			conflicts: []base.Conflict{
+				{Device: "D1", Actions: []string{"Standby", "PowerOn"}, Kept: "PowerOn", Policy: ConflictPrecedence},
+			},
+		},
+		{
+			name:       "neither has precedence",
+			policy:     ConflictPrecedence,
+			precedence: "Mute",
+			actions:    []base.ActionStructure{power("D1", "Standby", false), power("D1", "PowerOn", false)},
+			fails:      helpers.InvalidRequest,
+		},
+		{
+			name:    "drop both",
+			policy:  ConflictDropBoth,
+			actions: []base.ActionStructure{power("D1", "PowerOn", true), power("D1", "Standby", false), power("D2", "PowerOn", false)},
+			kept:    []string{"D2 PowerOn"},
+			conflicts: []base.Conflict{
+				{Device: "D1", Actions: []string{"PowerOn", "Standby"}, Policy: ConflictDropBoth},
+			},
+		},
+		{
+			name:    "unknown policy",
+			policy:  "FirstFieldWins",
+			actions: []base.ActionStructure{power("D1", "PowerOn", false)},
+			fails:   helpers.ConfigError,
+		},
+	}
+
+	for _, test := range tests {
+		output, conflicts, err := ResolveConflicts(test.actions, test.policy, ParsePrecedence(test.precedence))
+		if len(test.fails) > 0 {
+			if e, ok := err.(*helpers.Error); !ok || e.Code != test.fails {
+				t.Errorf("%s: expected the request to fail with %s, got %v", test.name, test.fails, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+
+		var kept []string
+		for _, action := range output {
+			kept = append(kept, action.Device.Name+" "+action.Action)
+		}
+		if strings.Join(kept, ",") != strings.Join(test.kept, ",") {
+			t.Errorf("%s: expected %v to be kept, got %v", test.name, test.kept, kept)
+		}
+
+		if len(conflicts) != len(test.conflicts) {
+			t.Errorf("%s: expected conflicts %+v, got %+v", test.name, test.conflicts, conflicts)
+			continue
+		}
+
+		for i := range conflicts {
+			got, expected := conflicts[i], test.conflicts[i]
+			if got.Device != expected.Device || got.Kept != expected.Kept || got.Policy != expected.Policy ||
+				strings.Join(got.Actions, ",") != strings.Join(expected.Actions, ",") {
+				t.Errorf("%s: expected conflict %+v, got %+v", test.name, expected, got)
+			}
+		}
+	}
+}
@@ -20,6 +20,22 @@ type DefaultReconciler struct{}
 //Reconcile sorts through the list of actions to determine the execution order.
 func (d *DefaultReconciler) Reconcile(actions []base.ActionStructure, inCount int) ([]base.ActionStructure, int, error) {
 
+	output, chains, count, err := ReconcileByDevice("DefaultReconciler", actions, inCount)
+	if err != nil {
+		return []base.ActionStructure{}, 0, err
+	}
+
+	// Screens and lifts have to move around the displays' power.
+	InterlockMotorized(&output[0], chains)
+
+	// Finally, we return the sorted list of actions.
+	return output, count, nil
+}
+
+//ReconcileByDevice builds a chain of actions for each device, sorted by priority, that all start at once.
+//It returns the DAG, starting with an overridden "Start" action, along with each device's chain so they can be ordered against each other.
+func ReconcileByDevice(reconciler string, actions []base.ActionStructure, inCount int) ([]base.ActionStructure, map[string][]base.ActionStructure, int, error) {
+
 	log.L.Info("[reconciler] Removing incompatible actions...")
 	var buffer bytes.Buffer
 
@@ -32,28 +48,25 @@ func (d *DefaultReconciler) Reconcile(actions []base.ActionStructure, inCount in
 	}
 
 	// Next we will make a list of actions to output.
-	output := []base.ActionStructure{
-		base.ActionStructure{
-			Action:              "Start",
-			Device:              structs.Device{ID: "DefaultReconciler"},
-			GeneratingEvaluator: "DefaultReconciler",
-			Overridden:          true,
-		},
-	}
+	output := []base.ActionStructure{startAction(reconciler)}
 
-	var count int
+	// StandardReconcile reports how many of each device's actions were overridden, as a negative count.
+	count := inCount
+
+	// Keep each device's chain of actions, so they can be ordered against each other.
+	chains := make(map[string][]base.ActionStructure)
 
 	// As we iterate through the actionMap, we will sort the actions by device and priority.
 	for device, actionList := range actionMap {
 
-		actionList, c, err := StandardReconcile(device, inCount, actionList)
+		actionList, c, err := StandardReconcile(device, 0, actionList)
 		if err != nil {
-			return []base.ActionStructure{}, 0, err
+			return []base.ActionStructure{}, nil, 0, err
 		}
 
 		actionList, err = SortActionsByPriority(actionList)
 		if err != nil {
-			return []base.ActionStructure{}, 0, err
+			return []base.ActionStructure{}, nil, 0, err
 		}
 
 		// Some actions are dependent on others, so we will map that relationship as well.
@@ -70,11 +83,111 @@ func (d *DefaultReconciler) Reconcile(actions []base.ActionStructure, inCount in
 		// After sorting, we add the sorted actions and their children to the output.
 		output[0].Children = append(output[0].Children, &actionList[0])
 		output = append(output, actionList...)
-		count = c
+		chains[device] = actionList
+		count += c
 	}
 
-	// Finally, we return the sorted list of actions.
-	return output, count, nil
+	return output, chains, count, nil
+}
+
+//startAction is the root of every DAG. It's never run itself, but its children are run at once.
+func startAction(reconciler string) base.ActionStructure {
+	return base.ActionStructure{
+		Action:              "Start",
+		Device:              structs.Device{ID: reconciler},
+		GeneratingEvaluator: reconciler,
+		Overridden:          true,
+	}
+}
+
+// InterlockMotorized orders screens and lifts around the power of the displays that project onto them: a display waits
+// to power on until every screen or lift it uses has lowered, and a screen or lift waits to raise until every display
+// using it has gone to standby. chains holds each device's actions, in the order they are run.
+func InterlockMotorized(start *base.ActionStructure, chains map[string][]base.ActionStructure) {
+	var lowering, raising, poweringOn, standingBy []*base.ActionStructure
+
+	for _, chain := range chains {
+		root := &chain[0]
+
+		if structs.HasRole(root.Device, base.ScreenRole) || structs.HasRole(root.Device, base.LiftRole) {
+			switch root.Action {
+			case "Lower":
+				lowering = append(lowering, root)
+			case "Raise":
+				raising = append(raising, root)
+			}
+			continue
+		}
+
+		if !structs.HasRole(root.Device, "VideoOut") {
+			continue
+		}
+
+		for i := range chain {
+			switch chain[i].Action {
+			case "PowerOn":
+				//the whole chain waits, since the display can't do anything else until it's on
+				poweringOn = append(poweringOn, root)
+			case "Standby":
+				standingBy = append(standingBy, &chain[i])
+			}
+		}
+	}
+
+	for _, display := range poweringOn {
+		var parents []*base.ActionStructure
+		for _, motorized := range lowering {
+			if base.ProjectsOnto(display.Device, motorized.Device) {
+				log.L.Infof("[reconciler] %s will power on after %s lowers", display.Device.Name, motorized.Device.Name)
+				parents = append(parents, motorized)
+			}
+		}
+
+		dependOn(start, parents, display)
+	}
+
+	for _, motorized := range raising {
+		var parents []*base.ActionStructure
+		for _, display := range standingBy {
+			if base.ProjectsOnto(display.Device, motorized.Device) {
+				log.L.Infof("[reconciler] %s will raise after %s goes to standby", motorized.Device.Name, display.Device.Name)
+				parents = append(parents, display)
+			}
+		}
+
+		dependOn(start, parents, motorized)
+	}
+}
+
+// dependOn makes a chain wait for every one of parents. A chain that starts at the beginning of the DAG is moved;
+// one that already waits on other actions keeps waiting on them too.
+func dependOn(start *base.ActionStructure, parents []*base.ActionStructure, root *base.ActionStructure) {
+	if len(parents) == 0 {
+		return
+	}
+
+	waiting := len(parents)
+	moved := false
+	for i := range start.Children {
+		if start.Children[i] == root {
+			start.Children = append(start.Children[:i], start.Children[i+1:]...)
+			moved = true
+			break
+		}
+	}
+
+	for _, parent := range parents {
+		parent.Children = append(parent.Children, root)
+	}
+
+	switch {
+	case root.Join != nil:
+		root.Join.Add(waiting)
+	case !moved:
+		root.Join = base.NewJoin(waiting + 1)
+	case waiting > 1:
+		root.Join = base.NewJoin(waiting)
+	}
 }
 
 // SortActionsByPriority sorts the list of actions by their priority integer value.
@@ -0,0 +1,189 @@
+package actionreconcilers
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/common/structs"
+)
+
+//device builds a device with a role, and a port to each of the devices it's connected to.
+func device(name, role string, connected ...string) structs.Device {
+	d := structs.Device{ID: "ITB-1101-" + name, Name: name, Roles: []structs.Role{{ID: role}}}
+	for _, c := range connected {
+		d.Ports = append(d.Ports, structs.Port{ID: c, DestinationDevice: c})
+	}
+
+	return d
+}
+
+//chainSpec is a device and the actions it runs, in order.
+type chainSpec struct {
+	device  structs.Device
+	actions []string
+}
+
+//buildDAG chains each device's actions in order and starts every chain at once, like ReconcileByDevice.
+func buildDAG(specs []chainSpec) (*base.ActionStructure, map[string][]base.ActionStructure) {
+	start := startAction("test")
+	chains := make(map[string][]base.ActionStructure)
+
+	for _, spec := range specs {
+		d := spec.device
+		chain := make([]base.ActionStructure, len(spec.actions))
+		for i, action := range spec.actions {
+			chain[i] = base.ActionStructure{Action: action, Device: d}
+		}
+		for i := 0; i < len(chain)-1; i++ {
+			chain[i].Children = append(chain[i].Children, &chain[i+1])
+		}
+
+		chains[d.ID] = chain
+		start.Children = append(start.Children, &chain[0])
+	}
+
+	return &start, chains
+}
+
+//run walks the DAG the way the executor does, and returns the order the actions ran in as "device action".
+func run(start *base.ActionStructure) []string {
+	var order []string
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+
+	var execute func(action *base.ActionStructure)
+	execute = func(action *base.ActionStructure) {
+		defer wg.Done()
+
+		mutex.Lock()
+		order = append(order, action.Device.Name+" "+action.Action)
+		mutex.Unlock()
+
+		for _, child := range action.Children {
+			if child.Join != nil && !child.Join.Arrive() {
+				continue
+			}
+			wg.Add(1)
+			go execute(child)
+		}
+	}
+
+	for _, child := range start.Children {
+		wg.Add(1)
+		go execute(child)
+	}
+	wg.Wait()
+
+	return order
+}
+
+//checkDAG makes sure roots are the actions that start right away, that every action runs once,
+//and that each action in after runs after every one of its dependencies.
+func checkDAG(t *testing.T, name string, start *base.ActionStructure, specs []chainSpec, after map[string][]string, expectedRoots []string) {
+	var roots []string
+	for _, child := range start.Children {
+		roots = append(roots, child.Device.Name+" "+child.Action)
+	}
+	sort.Strings(roots)
+	sort.Strings(expectedRoots)
+	if strings.Join(roots, ",") != strings.Join(expectedRoots, ",") {
+		t.Errorf("%s: expected %v to start right away, got %v", name, expectedRoots, roots)
+	}
+
+	order := run(start)
+	position := make(map[string]int)
+	for i, action := range order {
+		if _, ok := position[action]; ok {
+			t.Errorf("%s: %s ran more than once", name, action)
+		}
+		position[action] = i
+	}
+
+	total := 0
+	for _, spec := range specs {
+		total += len(spec.actions)
+	}
+	if len(order) != total {
+		t.Errorf("%s: expected %v actions to run, got %v", name, total, order)
+	}
+
+	for action, dependencies := range after {
+		for _, dependency := range dependencies {
+			if position[action] < position[dependency] {
+				t.Errorf("%s: %s ran before %s", name, action, dependency)
+			}
+		}
+	}
+}
+
+func TestInterlockMotorized(t *testing.T) {
+	tests := []struct {
+		name    string
+		actions []chainSpec
+		// each action must run after every one of its dependencies
+		after map[string][]string
+		// actions that should still start right away
+		roots []string
+	}{
+		{
+			name: "each projector waits for its own screen",
+			actions: []chainSpec{
+				{device("P1", "VideoOut"), []string{"PowerOn", "ChangeInput"}},
+				{device("P2", "VideoOut"), []string{"PowerOn"}},
+				{device("S1", base.ScreenRole, "P1"), []string{"Lower"}},
+				{device("S2", base.ScreenRole, "P2"), []string{"Lower"}},
+				{device("TV1", "VideoOut"), []string{"PowerOn"}},
+				{device("SW1", "VideoSwitcher", "P1", "P2"), []string{"ChangeInput"}},
+			},
+			after: map[string][]string{
+				"P1 PowerOn":     {"S1 Lower"},
+				"P1 ChangeInput": {"S1 Lower"},
+				"P2 PowerOn":     {"S2 Lower"},
+			},
+			roots: []string{"S1 Lower", "S2 Lower", "TV1 PowerOn", "SW1 ChangeInput"},
+		},
+		{
+			name: "a projector waits for every screen and lift it uses",
+			actions: []chainSpec{
+				{device("P1", "VideoOut", "L1"), []string{"PowerOn"}},
+				{device("S1", base.ScreenRole, "P1"), []string{"Lower"}},
+				{device("L1", base.LiftRole), []string{"Lower"}},
+			},
+			after: map[string][]string{
+				"P1 PowerOn": {"S1 Lower", "L1 Lower"},
+			},
+			roots: []string{"S1 Lower", "L1 Lower"},
+		},
+		{
+			name: "a shared screen waits for every projector to go to standby",
+			actions: []chainSpec{
+				{device("P1", "VideoOut"), []string{"Standby"}},
+				{device("P2", "VideoOut"), []string{"Standby"}},
+				{device("S1", base.ScreenRole, "P1", "P2"), []string{"Raise"}},
+			},
+			after: map[string][]string{
+				"S1 Raise": {"P1 Standby", "P2 Standby"},
+			},
+			roots: []string{"P1 Standby", "P2 Standby"},
+		},
+		{
+			name: "screens not used by a display aren't held back",
+			actions: []chainSpec{
+				{device("P1", "VideoOut"), []string{"PowerOn"}},
+				{device("S1", base.ScreenRole), []string{"Lower"}},
+				{device("SH1", base.ShadeRole), []string{"Lower"}},
+			},
+			roots: []string{"P1 PowerOn", "S1 Lower", "SH1 Lower"},
+		},
+	}
+
+	for _, test := range tests {
+		start, chains := buildDAG(test.actions)
+		InterlockMotorized(start, chains)
+
+		checkDAG(t, test.name, start, test.actions, test.after, test.roots)
+	}
+}
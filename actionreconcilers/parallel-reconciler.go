@@ -0,0 +1,53 @@
+package actionreconcilers
+
+import (
+	"strconv"
+
+	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/common/log"
+)
+
+//ParallelReconciler runs every action at once, for rooms where the order doesn't matter.
+type ParallelReconciler struct{}
+
+//Reconcile sorts through the list of actions to determine the execution order.
+func (p *ParallelReconciler) Reconcile(actions []base.ActionStructure, inCount int) ([]base.ActionStructure, int, error) {
+
+	log.L.Info("[reconciler] Reconciling actions to run in parallel...")
+
+	// Incompatible actions are still only checked within a device.
+	actionMap := make(map[string][]base.ActionStructure)
+	for _, action := range actions {
+		actionMap[action.Device.ID] = append(actionMap[action.Device.ID], action)
+	}
+
+	// StandardReconcile reports how many of each device's actions were overridden, as a negative count.
+	count := inCount
+	output := []base.ActionStructure{startAction("ParallelReconciler")}
+
+	for device, actionList := range actionMap {
+		actionList, c, err := StandardReconcile(device, 0, actionList)
+		if err != nil {
+			return []base.ActionStructure{}, 0, err
+		}
+
+		for _, action := range actionList {
+			if !action.Overridden {
+				output = append(output, action)
+			}
+		}
+		count += c
+	}
+
+	// Every action is a chain of its own, so only a display's PowerOn waits for its screens and lifts.
+	chains := make(map[string][]base.ActionStructure)
+	for i := range output[1:] {
+		output[0].Children = append(output[0].Children, &output[i+1])
+		chains[strconv.Itoa(i)] = output[i+1 : i+2]
+	}
+
+	// Screens and lifts still have to move around the displays' power.
+	InterlockMotorized(&output[0], chains)
+
+	return output, count, nil
+}
@@ -0,0 +1,250 @@
+package actionreconcilers
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/common/structs"
+)
+
+//typed gives a device a type with commands, at the priorities given, in order.
+func typed(d structs.Device, commands ...string) structs.Device {
+	for i, command := range commands {
+		d.Type.Commands = append(d.Type.Commands, structs.Command{ID: command, Priority: i + 1})
+	}
+
+	return d
+}
+
+//roomActions are two displays, each told to power on, while the room as a whole goes to standby.
+//StandbyDefault lists PowerOn as incompatible, so each display's room-wide Standby is overridden.
+func roomActions() []base.ActionStructure {
+	var actions []base.ActionStructure
+	for _, name := range []string{"D1", "D2"} {
+		d := typed(device(name, "VideoOut"), "Standby", "PowerOn", "ChangeInput")
+		actions = append(actions,
+			base.ActionStructure{Action: "Standby", Device: d, GeneratingEvaluator: "StandbyDefault"},
+			base.ActionStructure{Action: "PowerOn", Device: d, DeviceSpecific: true},
+		)
+	}
+
+	amp := typed(device("AMP1", "AudioOut"), "Standby", "PowerOn", "ChangeInput")
+	actions = append(actions, base.ActionStructure{Action: "ChangeInput", Device: amp})
+
+	return actions
+}
+
+func TestReconcilerCounts(t *testing.T) {
+	tests := []struct {
+		name       string
+		reconciler ActionReconciler
+	}{
+		{"strict priority", &StrictPriorityReconciler{}},
+		{"parallel", &ParallelReconciler{}},
+	}
+
+	for _, test := range tests {
+		actions := roomActions()
+		output, count, err := test.reconciler.Reconcile(actions, len(actions))
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+
+		//one override on each display
+		if count != len(actions)-2 {
+			t.Errorf("%s: expected %v actions to report, got %v", test.name, len(actions)-2, count)
+		}
+
+		if output[0].Action != "Start" || !output[0].Overridden {
+			t.Errorf("%s: expected the DAG to begin with an overridden Start, got %s", test.name, output[0].Action)
+		}
+	}
+}
+
+func TestStrictPriority(t *testing.T) {
+	actions := roomActions()
+	output, _, err := (&StrictPriorityReconciler{}).Reconcile(actions, len(actions))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//every action runs alone, in priority order, regardless of device
+	var order []string
+	for action := &output[0]; len(action.Children) > 0; action = action.Children[0] {
+		if len(action.Children) != 1 {
+			t.Fatalf("expected %s %s to have one child, got %v", action.Device.Name, action.Action, len(action.Children))
+		}
+		order = append(order, action.Children[0].Action)
+	}
+
+	expected := "Standby,Standby,PowerOn,PowerOn,ChangeInput"
+	if strings.Join(order, ",") != expected {
+		t.Errorf("expected %s, got %s", expected, strings.Join(order, ","))
+	}
+}
+
+func TestParallel(t *testing.T) {
+	actions := roomActions()
+	output, _, err := (&ParallelReconciler{}).Reconcile(actions, len(actions))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(output[0].Children) != len(actions) {
+		t.Errorf("expected all %v actions to start at once, got %v", len(actions), len(output[0].Children))
+	}
+
+	for _, action := range output[1:] {
+		if len(action.Children) > 0 {
+			t.Errorf("expected %s %s not to have anything waiting on it", action.Device.Name, action.Action)
+		}
+	}
+}
+
+func TestRoleBased(t *testing.T) {
+	videoFirst := DependencyRule{
+		Before: ActionMatcher{Role: "VideoOut", Action: "PowerOn"},
+		After:  ActionMatcher{Role: "VideoSwitcher", Action: "ChangeInput"},
+	}
+
+	tests := []struct {
+		name    string
+		actions []chainSpec
+		rules   []DependencyRule
+		after   map[string][]string
+		roots   []string
+	}{
+		{
+			name: "waits for every matching action",
+			actions: []chainSpec{
+				{device("D1", "VideoOut"), []string{"PowerOn"}},
+				{device("D2", "VideoOut"), []string{"PowerOn", "ChangeInput"}},
+				{device("SW1", "VideoSwitcher"), []string{"ChangeInput"}},
+			},
+			rules: []DependencyRule{videoFirst},
+			after: map[string][]string{
+				"SW1 ChangeInput": {"D1 PowerOn", "D2 PowerOn"},
+			},
+			roots: []string{"D1 PowerOn", "D2 PowerOn"},
+		},
+		{
+			name: "matches later in a chain",
+			actions: []chainSpec{
+				{device("D1", "VideoOut"), []string{"PowerOn"}},
+				{device("SW1", "VideoSwitcher"), []string{"PowerOn", "ChangeInput"}},
+			},
+			rules: []DependencyRule{videoFirst},
+			after: map[string][]string{
+				"SW1 ChangeInput": {"D1 PowerOn", "SW1 PowerOn"},
+			},
+			roots: []string{"D1 PowerOn", "SW1 PowerOn"},
+		},
+		{
+			name: "any action",
+			actions: []chainSpec{
+				{device("D1", "VideoOut"), []string{"PowerOn", "ChangeInput"}},
+				{device("AMP1", "AudioOut"), []string{"PowerOn", "SetVolume"}},
+			},
+			rules: []DependencyRule{{Before: ActionMatcher{Role: "VideoOut"}, After: ActionMatcher{Role: "AudioOut", Action: "SetVolume"}}},
+			after: map[string][]string{
+				"AMP1 SetVolume": {"D1 PowerOn", "D1 ChangeInput", "AMP1 PowerOn"},
+			},
+			roots: []string{"D1 PowerOn", "AMP1 PowerOn"},
+		},
+		{
+			name: "rules that would wait on each other",
+			actions: []chainSpec{
+				{device("D1", "VideoOut"), []string{"PowerOn"}},
+				{device("SW1", "VideoSwitcher"), []string{"ChangeInput"}},
+			},
+			rules: []DependencyRule{
+				videoFirst,
+				{Before: ActionMatcher{Role: "VideoSwitcher"}, After: ActionMatcher{Role: "VideoOut"}},
+			},
+			after: map[string][]string{
+				"SW1 ChangeInput": {"D1 PowerOn"},
+			},
+			roots: []string{"D1 PowerOn"},
+		},
+		{
+			name: "nothing matches",
+			actions: []chainSpec{
+				{device("D1", "VideoOut"), []string{"Standby"}},
+				{device("SW1", "VideoSwitcher"), []string{"ChangeInput"}},
+			},
+			rules: []DependencyRule{videoFirst},
+			roots: []string{"D1 Standby", "SW1 ChangeInput"},
+		},
+	}
+
+	for _, test := range tests {
+		start, chains := buildDAG(test.actions)
+		for _, rule := range test.rules {
+			applyRule(start, chains, rule)
+		}
+
+		checkDAG(t, test.name, start, test.actions, test.after, test.roots)
+	}
+}
+
+//motorizedActions power one projector on and another off, while their screens are lowered and raised.
+func motorizedActions() []base.ActionStructure {
+	p1 := typed(device("P1", "VideoOut"), "PowerOn", "Raise", "Lower", "Standby")
+	p2 := typed(device("P2", "VideoOut"), "PowerOn", "Raise", "Lower", "Standby")
+	s1 := typed(device("S1", base.ScreenRole, "P1"), "PowerOn", "Raise", "Lower", "Standby")
+	s2 := typed(device("S2", base.ScreenRole, "P2"), "PowerOn", "Raise", "Lower", "Standby")
+
+	return []base.ActionStructure{
+		{Action: "PowerOn", Device: p1},
+		{Action: "Lower", Device: s1},
+		{Action: "Standby", Device: p2},
+		{Action: "Raise", Device: s2},
+	}
+}
+
+func TestParallelInterlock(t *testing.T) {
+	actions := motorizedActions()
+	output, _, err := (&ParallelReconciler{}).Reconcile(actions, len(actions))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var roots []string
+	for _, child := range output[0].Children {
+		roots = append(roots, child.Device.Name+" "+child.Action)
+	}
+	sort.Strings(roots)
+	if strings.Join(roots, ",") != "P2 Standby,S1 Lower" {
+		t.Errorf("expected only S1 Lower and P2 Standby to start right away, got %v", roots)
+	}
+
+	waits := map[string]string{"S1 Lower": "P1 PowerOn", "P2 Standby": "S2 Raise"}
+	for _, action := range output[1:] {
+		name := action.Device.Name + " " + action.Action
+		if expected, ok := waits[name]; ok && (len(action.Children) != 1 || action.Children[0].Device.Name+" "+action.Children[0].Action != expected) {
+			t.Errorf("expected %s to wait for %s", expected, name)
+		}
+	}
+}
+
+func TestStrictPriorityInterlock(t *testing.T) {
+	actions := motorizedActions()
+	output, _, err := (&StrictPriorityReconciler{}).Reconcile(actions, len(actions))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//by priority alone, P1 would power on, and S2 would raise, before either screen was taken care of
+	var order []string
+	for action := &output[0]; len(action.Children) > 0; action = action.Children[0] {
+		order = append(order, action.Children[0].Device.Name+" "+action.Children[0].Action)
+	}
+
+	expected := "S1 Lower,P1 PowerOn,P2 Standby,S2 Raise"
+	if strings.Join(order, ",") != expected {
+		t.Errorf("expected %s, got %s", expected, strings.Join(order, ","))
+	}
+}
@@ -0,0 +1,146 @@
+package actionreconcilers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/common/log"
+	"github.com/byuoitav/common/structs"
+	"github.com/fatih/color"
+)
+
+//RoleBasedReconciler sorts each device's actions by priority, like the DefaultReconciler,
+//then orders the devices against each other with the dependency rules in RECONCILER_RULES_PATH.
+type RoleBasedReconciler struct{}
+
+//ActionMatcher matches the actions on devices with Role. An empty Action matches any action.
+type ActionMatcher struct {
+	Role   string `json:"role"`
+	Action string `json:"action,omitempty"`
+}
+
+//DependencyRule makes the actions matching After wait for an action matching Before,
+//e.g. the VideoSwitcher's ChangeInput waits for the VideoOut's PowerOn.
+type DependencyRule struct {
+	Before ActionMatcher `json:"before"`
+	After  ActionMatcher `json:"after"`
+}
+
+var rules []DependencyRule
+
+//LoadDependencyRules reads the rules in the file at RECONCILER_RULES_PATH. There are no rules if it isn't set.
+func LoadDependencyRules() error {
+	path := os.Getenv("RECONCILER_RULES_PATH")
+	if len(path) == 0 {
+		log.L.Warnf("[reconciler] RECONCILER_RULES_PATH isn't set, so there are no dependency rules")
+		return nil
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read dependency rules: %s", err.Error())
+	}
+
+	var config struct {
+		Rules []DependencyRule `json:"rules"`
+	}
+
+	err = json.Unmarshal(b, &config)
+	if err != nil {
+		return fmt.Errorf("unable to parse dependency rules in %s: %s", path, err.Error())
+	}
+
+	rules = config.Rules
+	log.L.Infof("%s", color.HiBlueString("[reconciler] loaded %v dependency rules from %s", len(rules), path))
+	return nil
+}
+
+//GetDependencyRules returns the rules LoadDependencyRules read.
+func GetDependencyRules() []DependencyRule {
+	return rules
+}
+
+//Reconcile sorts through the list of actions to determine the execution order.
+func (r *RoleBasedReconciler) Reconcile(actions []base.ActionStructure, inCount int) ([]base.ActionStructure, int, error) {
+
+	output, chains, count, err := ReconcileByDevice("RoleBasedReconciler", actions, inCount)
+	if err != nil {
+		return []base.ActionStructure{}, 0, err
+	}
+
+	for _, rule := range GetDependencyRules() {
+		applyRule(&output[0], chains, rule)
+	}
+
+	return output, count, nil
+}
+
+//applyRule makes every action matching After wait for every action on another device matching Before.
+func applyRule(start *base.ActionStructure, chains map[string][]base.ActionStructure, rule DependencyRule) {
+	var befores []*base.ActionStructure
+	for _, chain := range chains {
+		for i := range chain {
+			if rule.Before.matches(chain[i]) {
+				befores = append(befores, &chain[i])
+			}
+		}
+	}
+
+	if len(befores) == 0 {
+		return
+	}
+
+	for _, chain := range chains {
+		for i := range chain {
+			after := &chain[i]
+			if !rule.After.matches(*after) {
+				continue
+			}
+
+			var parents []*base.ActionStructure
+			for _, before := range befores {
+				if before.Device.ID == after.Device.ID {
+					continue
+				}
+
+				//if the before action already waits on this one, neither would ever run
+				if reaches(after, before) {
+					log.L.Warnf("[reconciler] %s %s can't wait for %s %s, it would be waiting on itself", after.Device.Name, after.Action, before.Device.Name, before.Action)
+					continue
+				}
+
+				log.L.Infof("[reconciler] %s %s will wait for %s %s", after.Device.Name, after.Action, before.Device.Name, before.Action)
+				parents = append(parents, before)
+			}
+
+			dependOn(start, parents, after)
+		}
+	}
+}
+
+func (m ActionMatcher) matches(action base.ActionStructure) bool {
+	if !structs.HasRole(action.Device, m.Role) {
+		return false
+	}
+
+	return len(m.Action) == 0 || strings.EqualFold(m.Action, action.Action)
+}
+
+//reaches reports whether target is from, or runs after it.
+func reaches(from, target *base.ActionStructure) bool {
+	if from == target {
+		return true
+	}
+
+	for _, child := range from.Children {
+		if reaches(child, target) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,115 @@
+package actionreconcilers
+
+import (
+	"math"
+	"sort"
+
+	"github.com/byuoitav/av-api/base"
+	ce "github.com/byuoitav/av-api/commandevaluators"
+	"github.com/byuoitav/common/log"
+	"github.com/byuoitav/common/structs"
+)
+
+//StrictPriorityReconciler orders every action in the room by priority, regardless of device,
+//and runs them one at a time, e.g. every amp is turned off before any source is.
+type StrictPriorityReconciler struct{}
+
+//Reconcile sorts through the list of actions to determine the execution order.
+func (s *StrictPriorityReconciler) Reconcile(actions []base.ActionStructure, inCount int) ([]base.ActionStructure, int, error) {
+
+	log.L.Info("[reconciler] Reconciling actions by strict priority...")
+
+	// Incompatible actions are still only checked within a device.
+	actionMap := make(map[string][]base.ActionStructure)
+	for _, action := range actions {
+		actionMap[action.Device.ID] = append(actionMap[action.Device.ID], action)
+	}
+
+	// StandardReconcile reports how many of each device's actions were overridden, as a negative count.
+	count := inCount
+	output := []base.ActionStructure{startAction("StrictPriorityReconciler")}
+
+	for device, actionList := range actionMap {
+		actionList, c, err := StandardReconcile(device, 0, actionList)
+		if err != nil {
+			return []base.ActionStructure{}, 0, err
+		}
+
+		for _, action := range actionList {
+			if !action.Overridden {
+				output = append(output, action)
+			}
+		}
+		count += c
+	}
+
+	sort.SliceStable(output[1:], func(i, j int) bool {
+		return priorityOf(output[i+1]) < priorityOf(output[j+1])
+	})
+
+	// Screens and lifts still have to move around the displays' power.
+	output = append(output[:1], interlockSequence(output[1:])...)
+
+	// Each action is the only child of the one before it.
+	for i := 0; i < len(output)-1; i++ {
+		output[i].Children = []*base.ActionStructure{&output[i+1]}
+	}
+
+	return output, count, nil
+}
+
+//priorityOf returns the priority of an action's command on its device. Actions with no priority go last.
+func priorityOf(action base.ActionStructure) int {
+	ok, command := ce.CheckCommands(action.Device.Type.Commands, action.Action)
+	if !ok {
+		return math.MaxInt32
+	}
+
+	return command.Priority
+}
+
+//interlockSequence moves screens and lifts in a sequence of actions, the way InterlockMotorized orders them in a DAG:
+//a screen or lift is lowered before any display using it powers on, and raised after every display using it goes to standby.
+func interlockSequence(actions []base.ActionStructure) []base.ActionStructure {
+	for _, motion := range []string{"Lower", "Raise"} {
+		for i := 0; i < len(actions); i++ {
+			motorized := actions[i]
+			if motorized.Action != motion || !(structs.HasRole(motorized.Device, base.ScreenRole) || structs.HasRole(motorized.Device, base.LiftRole)) {
+				continue
+			}
+
+			// lowering moves before the first display powering on, raising after the last display going to standby
+			target := -1
+			for j, display := range actions {
+				if !structs.HasRole(display.Device, "VideoOut") || !base.ProjectsOnto(display.Device, motorized.Device) {
+					continue
+				}
+
+				if motion == "Lower" && display.Action == "PowerOn" && j < i && target == -1 {
+					target = j
+				}
+				if motion == "Raise" && display.Action == "Standby" && j > i {
+					target = j
+				}
+			}
+
+			if target == -1 {
+				continue
+			}
+
+			log.L.Infof("[reconciler] moving %s %s around the displays using it", motorized.Device.Name, motorized.Action)
+
+			actions = append(actions[:i], actions[i+1:]...)
+			if motion == "Raise" {
+				// everything after i moved down one, so the display is now at target-1
+				target--
+				i--
+				actions = append(actions[:target+1], append([]base.ActionStructure{motorized}, actions[target+1:]...)...)
+			} else {
+				actions = append(actions[:target], append([]base.ActionStructure{motorized}, actions[target:]...)...)
+			}
+		}
+	}
+
+	return actions
+}
@@ -0,0 +1,298 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/common/log"
+)
+
+// DefaultPath is where the audit log is written if AUDIT_LOG_PATH is not set.
+const DefaultPath = "audit.jsonl"
+
+// DefaultMaxSize is how big the audit log gets before it's rotated, if AUDIT_LOG_MAX_SIZE is not set.
+const DefaultMaxSize = 10 * 1024 * 1024
+
+// DefaultMaxFiles is how many rotated audit logs are kept, if AUDIT_LOG_MAX_FILES is not set.
+const DefaultMaxFiles = 5
+
+// maxRecordSize is the largest single record the store will read back.
+const maxRecordSize = 1024 * 1024
+
+// Record is a single SetRoomState call, as it was requested and as it was carried out.
+type Record struct {
+	ID         string          `json:"id"`
+	Building   string          `json:"building"`
+	Room       string          `json:"room"`
+	Requestor  string          `json:"requestor"`
+	Body       base.PublicRoom `json:"body"`
+	Actions    []Action        `json:"actions"`
+	Error      string          `json:"error,omitempty"`
+	Start      time.Time       `json:"start"`
+	End        time.Time       `json:"end"`
+	DurationMS int64           `json:"durationMs"`
+}
+
+// Action is an action generated for a request and what happened when it was executed.
+type Action struct {
+	Action     string            `json:"action"`
+	Device     string            `json:"device"`
+	Evaluator  string            `json:"evaluator"`
+	Parameters map[string]string `json:"parameters,omitempty"`
+	Outcome    string            `json:"outcome"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// The possible outcomes of an action.
+const (
+	OutcomeSuccess    = "success"
+	OutcomeFailure    = "failure"
+	OutcomeOverridden = "overridden"
+	OutcomeNotRun     = "not-run"
+)
+
+// Filter narrows down the records returned by a query. Empty fields match everything.
+type Filter struct {
+	Building  string
+	Room      string
+	Requestor string
+	Since     time.Time
+	Offset    int
+	Limit     int
+}
+
+// Page is a single page of query results. Next is the offset of the following page, or -1 if there isn't one.
+type Page struct {
+	Records []Record `json:"records"`
+	Next    int      `json:"next"`
+}
+
+// Store is an append-only store of audit records, kept as one JSON object per line. Once the file
+// passes its size limit, it's moved to path.1, path.1 to path.2, and so on, and the oldest is deleted.
+type Store struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+	mutex    sync.Mutex
+}
+
+// NewStore returns a store that reads and writes the file at path. Zero limits get the defaults.
+func NewStore(path string, maxSize int64, maxFiles int) *Store {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	if maxFiles <= 0 {
+		maxFiles = DefaultMaxFiles
+	}
+
+	return &Store{path: path, maxSize: maxSize, maxFiles: maxFiles}
+}
+
+var defaultStore *Store
+var defaultStoreOnce sync.Once
+
+// GetStore returns the store at AUDIT_LOG_PATH, or DefaultPath if that isn't set, limited by
+// AUDIT_LOG_MAX_SIZE (in bytes) and AUDIT_LOG_MAX_FILES.
+func GetStore() *Store {
+	defaultStoreOnce.Do(func() {
+		path := os.Getenv("AUDIT_LOG_PATH")
+		if len(path) == 0 {
+			path = DefaultPath
+		}
+
+		maxSize, err := strconv.ParseInt(os.Getenv("AUDIT_LOG_MAX_SIZE"), 10, 64)
+		if err != nil && len(os.Getenv("AUDIT_LOG_MAX_SIZE")) > 0 {
+			log.L.Warnf("[audit] invalid AUDIT_LOG_MAX_SIZE %q, using %d", os.Getenv("AUDIT_LOG_MAX_SIZE"), DefaultMaxSize)
+		}
+
+		maxFiles, err := strconv.Atoi(os.Getenv("AUDIT_LOG_MAX_FILES"))
+		if err != nil && len(os.Getenv("AUDIT_LOG_MAX_FILES")) > 0 {
+			log.L.Warnf("[audit] invalid AUDIT_LOG_MAX_FILES %q, using %d", os.Getenv("AUDIT_LOG_MAX_FILES"), DefaultMaxFiles)
+		}
+
+		defaultStore = NewStore(path, maxSize, maxFiles)
+	})
+
+	return defaultStore
+}
+
+// NewRecord starts a record for a request to change the state of a room.
+func NewRecord(body base.PublicRoom, requestor string) Record {
+	start := time.Now()
+
+	return Record{
+		ID:        fmt.Sprintf("%v-%v-%d", body.Building, body.Room, start.UnixNano()),
+		Building:  body.Building,
+		Room:      body.Room,
+		Requestor: requestor,
+		Body:      body,
+		Start:     start,
+	}
+}
+
+// Finish stamps the end time of the record, along with the error that ended the request, if there was one.
+func (r *Record) Finish(err error) {
+	r.End = time.Now()
+	r.DurationMS = int64(r.End.Sub(r.Start) / time.Millisecond)
+
+	if err != nil {
+		r.Error = err.Error()
+	}
+}
+
+// Append writes a record to the end of the store.
+func (s *Store) Append(record Record) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if info, err := os.Stat(s.path); err == nil && info.Size()+int64(len(b))+1 > s.maxSize {
+		err = s.rotate()
+		if err != nil {
+			return fmt.Errorf("unable to rotate %s: %s", s.path, err.Error())
+		}
+	}
+
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(b, '\n'))
+	return err
+}
+
+//rotate shifts each old file up one. Must be called with the mutex held.
+func (s *Store) rotate() error {
+	os.Remove(fmt.Sprintf("%s.%d", s.path, s.maxFiles))
+
+	for i := s.maxFiles - 1; i > 0; i-- {
+		old := fmt.Sprintf("%s.%d", s.path, i)
+		if _, err := os.Stat(old); err == nil {
+			err = os.Rename(old, fmt.Sprintf("%s.%d", s.path, i+1))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return os.Rename(s.path, s.path+".1")
+}
+
+// Query returns the page of records that match the filter, oldest first, across the current and rotated files.
+// The mutex is only held while the files are opened, so writers aren't blocked while they're read.
+func (s *Store) Query(filter Filter) (Page, error) {
+	page := Page{Records: []Record{}, Next: -1}
+
+	readers, closers, err := s.open()
+	defer func() {
+		for _, c := range closers {
+			c.Close()
+		}
+	}()
+	if err != nil {
+		return page, err
+	}
+
+	matched := 0
+	for _, reader := range readers {
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(make([]byte, 64*1024), maxRecordSize)
+
+		for scanner.Scan() {
+			var record Record
+			err := json.Unmarshal(scanner.Bytes(), &record)
+			if err != nil {
+				log.L.Warnf("[audit] skipping unreadable record: %s", err.Error())
+				continue
+			}
+
+			if !filter.matches(record) {
+				continue
+			}
+
+			matched++
+			if matched <= filter.Offset {
+				continue
+			}
+
+			if len(page.Records) == filter.Limit {
+				page.Next = filter.Offset + filter.Limit
+				return page, nil
+			}
+
+			page.Records = append(page.Records, record)
+		}
+
+		if err := scanner.Err(); err != nil {
+			return page, err
+		}
+	}
+
+	return page, nil
+}
+
+//open opens every file in the store, oldest first, each limited to what had been written when it was opened.
+//Files rotated after this still read correctly, since they're read through the handles opened here.
+func (s *Store) open() ([]io.Reader, []io.Closer, error) {
+	var readers []io.Reader
+	var closers []io.Closer
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	paths := []string{}
+	for i := s.maxFiles; i > 0; i-- {
+		paths = append(paths, fmt.Sprintf("%s.%d", s.path, i))
+	}
+	paths = append(paths, s.path)
+
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return readers, closers, err
+		}
+		closers = append(closers, file)
+
+		info, err := file.Stat()
+		if err != nil {
+			return readers, closers, err
+		}
+
+		readers = append(readers, io.LimitReader(file, info.Size()))
+	}
+
+	return readers, closers, nil
+}
+
+func (f Filter) matches(record Record) bool {
+	if len(f.Building) > 0 && !strings.EqualFold(f.Building, record.Building) {
+		return false
+	}
+
+	if len(f.Room) > 0 && !strings.EqualFold(f.Room, record.Room) {
+		return false
+	}
+
+	if len(f.Requestor) > 0 && !strings.EqualFold(f.Requestor, record.Requestor) {
+		return false
+	}
+
+	return !record.Start.Before(f.Since)
+}
@@ -0,0 +1,319 @@
+package authorization
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/common/log"
+	"github.com/fatih/color"
+)
+
+// The operations a policy can grant. Control grants every operation that changes state,
+// and Admin grants everything, including operations that bypass the evaluators.
+const (
+	Read    = "read"
+	Power   = "power"
+	Input   = "input"
+	Blank   = "blank"
+	Volume  = "volume"
+	Mute    = "mute"
+	Move    = "move"
+	Lock    = "lock"
+	Control = "control"
+	Admin   = "admin"
+)
+
+// Wildcard matches any subject, building, room or device.
+const Wildcard = "*"
+
+// Policy grants a set of callers some operations on some buildings, rooms, and devices.
+// An empty Buildings, Rooms, or Devices list matches everything.
+type Policy struct {
+	Subjects   []string `json:"subjects"`
+	Buildings  []string `json:"buildings,omitempty"`
+	Rooms      []string `json:"rooms,omitempty"`
+	Devices    []string `json:"devices,omitempty"`
+	Operations []string `json:"operations"`
+}
+
+// Permission is a single operation against a device in a room. An empty Device means the whole room.
+type Permission struct {
+	Device    string
+	Operation string
+}
+
+// Request is everything needed to decide if a caller may do something to a room.
+type Request struct {
+	Subjects    []string
+	Building    string
+	Room        string
+	Permissions []Permission
+}
+
+// Policies is the list of policies a request is checked against.
+type Policies []Policy
+
+var policies Policies
+var initialized bool
+var disabled bool
+
+// Init loads the policies in AUTHORIZATION_POLICY_PATH, and the key bearer tokens are verified with.
+// It must be called, and succeed, before any request is authorized. Without a policy file, every authenticated
+// caller may do anything but admin operations, as before policies existed; this is logged loudly at startup.
+// With a policy file, a key to verify tokens is required, since otherwise no caller could be identified.
+func Init() error {
+	path := os.Getenv("AUTHORIZATION_POLICY_PATH")
+	if len(path) == 0 {
+		if strings.EqualFold(os.Getenv("AUTHORIZATION_DISABLED"), "true") {
+			log.L.Warnf("[authorization] AUTHORIZATION_DISABLED is set: every authenticated caller may do anything except admin operations")
+		} else {
+			log.L.Warnf("%s", color.HiRedString("[authorization] AUTHORIZATION_POLICY_PATH is not set: every authenticated caller may do anything except admin operations. Set it to restrict callers, or AUTHORIZATION_DISABLED=true to silence this warning"))
+		}
+
+		disabled = true
+		initialized = true
+		return nil
+	}
+
+	p, err := load(path)
+	if err != nil {
+		return fmt.Errorf("unable to load authorization policies from %s: %s", path, err.Error())
+	}
+
+	keyFunc, err = loadKey()
+	if err != nil {
+		return fmt.Errorf("unable to load the bearer token key: %s", err.Error())
+	}
+
+	if keyFunc == nil {
+		return fmt.Errorf("policies are loaded from %s, but neither AUTHORIZATION_JWT_KEY_PATH nor AUTHORIZATION_JWT_SECRET is set to verify bearer tokens with", path)
+	}
+
+	policies = p
+	disabled = false
+	initialized = true
+	return nil
+}
+
+// Enabled reports whether policies are being enforced. It's false before Init, and when authorization has been disabled.
+func Enabled() bool {
+	return initialized && !disabled
+}
+
+// Authorize checks the request against the policies in AUTHORIZATION_POLICY_PATH.
+// It returns an error describing the first permission that was denied. Every request is denied until Init has succeeded,
+// and while authorization is disabled, admin operations are still denied.
+func Authorize(req Request) error {
+	if !initialized {
+		return fmt.Errorf("authorization hasn't been configured")
+	}
+
+	if disabled {
+		// operations that bypass the evaluators or other callers' locks need someone to have been granted them
+		for _, perm := range req.Permissions {
+			if strings.EqualFold(perm.Operation, Admin) {
+				return fmt.Errorf("admin operations are refused while authorization is disabled; set AUTHORIZATION_POLICY_PATH to grant them")
+			}
+		}
+
+		return nil
+	}
+
+	return policies.Authorize(req)
+}
+
+func load(path string) (Policies, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var p struct {
+		Policies Policies `json:"policies"`
+	}
+
+	err = json.Unmarshal(b, &p)
+	if err != nil {
+		return nil, err
+	}
+
+	log.L.Infof("[authorization] loaded %v policies from %s", len(p.Policies), path)
+	return p.Policies, nil
+}
+
+// Authorize checks that every permission in the request is granted by at least one policy.
+func (p Policies) Authorize(req Request) error {
+	for _, perm := range req.Permissions {
+		allowed := false
+		for _, policy := range p {
+			if policy.allows(req.Subjects, req.Building, req.Room, perm) {
+				allowed = true
+				break
+			}
+		}
+
+		if !allowed {
+			target := fmt.Sprintf("%s-%s", req.Building, req.Room)
+			if len(perm.Device) > 0 {
+				target = fmt.Sprintf("%s-%s", target, perm.Device)
+			}
+
+			caller := strings.Join(req.Subjects, "/")
+			if len(caller) == 0 {
+				caller = "an unidentified caller"
+			}
+
+			return fmt.Errorf("%s is not allowed to %s %s", caller, perm.Operation, target)
+		}
+	}
+
+	return nil
+}
+
+func (p Policy) allows(subjects []string, building, room string, perm Permission) bool {
+	// a wildcard policy also covers callers without a verified identity
+	matched := matches(p.Subjects, Wildcard)
+	for _, subject := range subjects {
+		if matches(p.Subjects, subject) {
+			matched = true
+			break
+		}
+	}
+
+	if !matched {
+		return false
+	}
+
+	if len(p.Buildings) > 0 && !matches(p.Buildings, building) {
+		return false
+	}
+
+	if len(p.Rooms) > 0 && !matches(p.Rooms, room) && !matches(p.Rooms, fmt.Sprintf("%s-%s", building, room)) {
+		return false
+	}
+
+	// a policy limited to certain devices can't be used for a room-wide request
+	if len(p.Devices) > 0 && (len(perm.Device) == 0 || !matches(p.Devices, perm.Device)) {
+		return false
+	}
+
+	for _, op := range p.Operations {
+		switch {
+		case strings.EqualFold(op, Admin):
+			return true
+		case strings.EqualFold(op, Control) && !strings.EqualFold(perm.Operation, Read) && !strings.EqualFold(perm.Operation, Admin):
+			return true
+		case strings.EqualFold(op, perm.Operation):
+			return true
+		}
+	}
+
+	return false
+}
+
+func matches(list []string, value string) bool {
+	for _, item := range list {
+		if item == Wildcard || strings.EqualFold(item, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RequiredPermissions lists the permissions needed to apply a PUT body to a room.
+func RequiredPermissions(room base.PublicRoom) []Permission {
+	var perms []Permission
+
+	if len(room.CurrentVideoInput) > 0 || len(room.CurrentAudioInput) > 0 {
+		perms = append(perms, Permission{Operation: Input})
+	}
+	if len(room.Power) > 0 {
+		perms = append(perms, Permission{Operation: Power})
+	}
+	if room.Blanked != nil {
+		perms = append(perms, Permission{Operation: Blank})
+	}
+	if room.Muted != nil {
+		perms = append(perms, Permission{Operation: Mute})
+	}
+	if room.Volume != nil {
+		perms = append(perms, Permission{Operation: Volume})
+	}
+
+	for _, display := range room.Displays {
+		perms = append(perms, devicePermissions(display.Device)...)
+		if display.Blanked != nil {
+			perms = append(perms, Permission{Device: display.Name, Operation: Blank})
+		}
+		//anything else is only acted on by configured evaluators
+		if len(display.Info) > 0 {
+			perms = append(perms, Permission{Device: display.Name, Operation: Control})
+		}
+	}
+
+	for _, audioDevice := range room.AudioDevices {
+		perms = append(perms, devicePermissions(audioDevice.Device)...)
+		if audioDevice.Muted != nil {
+			perms = append(perms, Permission{Device: audioDevice.Name, Operation: Mute})
+		}
+		if audioDevice.Volume != nil {
+			perms = append(perms, Permission{Device: audioDevice.Name, Operation: Volume})
+		}
+		if len(audioDevice.Info) > 0 {
+			perms = append(perms, Permission{Device: audioDevice.Name, Operation: Control})
+		}
+	}
+
+	for _, list := range [][]base.Motorized{room.Screens, room.Lifts, room.Shades} {
+		for _, motorized := range list {
+			if len(motorized.State) > 0 || motorized.Position != nil {
+				perms = append(perms, Permission{Device: motorized.Name, Operation: Move})
+			}
+		}
+	}
+
+	//fields PublicRoom doesn't have are only acted on by configured evaluators
+	if len(base.UnknownFields(room.Fields, room)) > 0 {
+		perms = append(perms, Permission{Operation: Control})
+	}
+
+	kinds := map[string]interface{}{
+		"displays":     base.Display{},
+		"audioDevices": base.AudioDevice{},
+		"screens":      base.Motorized{},
+		"lifts":        base.Motorized{},
+		"shades":       base.Motorized{},
+	}
+
+	for _, list := range base.DeviceLists {
+		kind := kinds[list]
+		entries, _ := room.Fields[list].([]interface{})
+		for _, entry := range entries {
+			fields, _ := entry.(map[string]interface{})
+			if len(base.UnknownFields(fields, kind)) > 0 {
+				name, _ := fields["name"].(string)
+				perms = append(perms, Permission{Device: name, Operation: Control})
+			}
+		}
+	}
+
+	return perms
+}
+
+func devicePermissions(device base.Device) []Permission {
+	var perms []Permission
+
+	if len(device.Power) > 0 {
+		perms = append(perms, Permission{Device: device.Name, Operation: Power})
+	}
+	if len(device.Input) > 0 {
+		perms = append(perms, Permission{Device: device.Name, Operation: Input})
+	}
+
+	return perms
+}
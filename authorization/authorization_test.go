@@ -0,0 +1,259 @@
+package authorization
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/byuoitav/av-api/base"
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+var testPolicies = Policies{
+	Policy{
+		Subjects:   []string{"ITB-1101-CP1"},
+		Buildings:  []string{"ITB"},
+		Rooms:      []string{"1101"},
+		Operations: []string{Control, Read},
+	},
+	Policy{
+		Subjects:   []string{"volume-panel"},
+		Rooms:      []string{"ITB-1101"},
+		Devices:    []string{"D1"},
+		Operations: []string{Volume},
+	},
+	Policy{
+		Subjects:   []string{"monitoring"},
+		Operations: []string{Read},
+	},
+	Policy{
+		Subjects:   []string{"tech"},
+		Operations: []string{Admin},
+	},
+}
+
+func TestControlPolicy(t *testing.T) {
+	req := Request{
+		Subjects:    []string{"ITB-1101-CP1"},
+		Building:    "ITB",
+		Room:        "1101",
+		Permissions: []Permission{{Operation: Power}, {Device: "D1", Operation: Input}},
+	}
+
+	if err := testPolicies.Authorize(req); err != nil {
+		t.Errorf("expected control of its own room to be allowed: %s", err)
+	}
+
+	req.Room = "1102"
+	if err := testPolicies.Authorize(req); err == nil {
+		t.Error("expected control of another room to be denied")
+	}
+}
+
+func TestDevicePolicy(t *testing.T) {
+	volume := 30
+	req := Request{
+		Subjects: []string{"volume-panel"},
+		Building: "ITB",
+		Room:     "1101",
+		Permissions: RequiredPermissions(base.PublicRoom{
+			AudioDevices: []base.AudioDevice{{Device: base.Device{Name: "D1"}, Volume: &volume}},
+		}),
+	}
+
+	if err := testPolicies.Authorize(req); err != nil {
+		t.Errorf("expected device volume to be allowed: %s", err)
+	}
+
+	req.Permissions = RequiredPermissions(base.PublicRoom{Volume: &volume})
+	if err := testPolicies.Authorize(req); err == nil {
+		t.Error("expected room-wide volume to be denied to a device policy")
+	}
+
+	req.Permissions = RequiredPermissions(base.PublicRoom{Power: "on"})
+	if err := testPolicies.Authorize(req); err == nil {
+		t.Error("expected power to be denied to a volume policy")
+	}
+}
+
+func TestReadAndAdminPolicies(t *testing.T) {
+	req := Request{
+		Subjects:    []string{"monitoring"},
+		Building:    "EB",
+		Room:        "201",
+		Permissions: []Permission{{Operation: Read}},
+	}
+
+	if err := testPolicies.Authorize(req); err != nil {
+		t.Errorf("expected read to be allowed: %s", err)
+	}
+
+	req.Permissions = []Permission{{Operation: Power}}
+	if err := testPolicies.Authorize(req); err == nil {
+		t.Error("expected power to be denied to a read-only policy")
+	}
+
+	req.Subjects = []string{"tech"}
+	req.Permissions = []Permission{{Operation: Admin}, {Device: "D1", Operation: Power}}
+	if err := testPolicies.Authorize(req); err != nil {
+		t.Errorf("expected admin to be allowed everything: %s", err)
+	}
+
+	req.Subjects = []string{"nobody"}
+	req.Permissions = []Permission{{Operation: Read}}
+	if err := testPolicies.Authorize(req); err == nil {
+		t.Error("expected an unknown subject to be denied")
+	}
+}
+
+func TestWildcardPolicy(t *testing.T) {
+	p := Policies{Policy{Subjects: []string{Wildcard}, Operations: []string{Read}}}
+	req := Request{Building: "ITB", Room: "1101", Permissions: []Permission{{Operation: Read}}}
+
+	if err := p.Authorize(req); err != nil {
+		t.Errorf("expected a wildcard policy to allow an unidentified caller: %s", err)
+	}
+
+	req.Permissions = []Permission{{Operation: Power}}
+	if err := p.Authorize(req); err == nil {
+		t.Error("expected an unidentified caller to be denied what the wildcard policy doesn't grant")
+	}
+}
+
+func TestSubject(t *testing.T) {
+	keyFunc = hmacKey([]byte("secret"))
+	defer func() { keyFunc = nil }()
+
+	sign := func(method jwt.SigningMethod, key interface{}, claims jwt.StandardClaims) string {
+		s, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		if err != nil {
+			t.Fatalf("unable to sign token: %s", err)
+		}
+		return s
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		subject string
+	}{
+		{"signed", sign(jwt.SigningMethodHS256, []byte("secret"), jwt.StandardClaims{Subject: "tech"}), "tech"},
+		{"wrong key", sign(jwt.SigningMethodHS256, []byte("guess"), jwt.StandardClaims{Subject: "tech"}), ""},
+		{"unsigned", sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, jwt.StandardClaims{Subject: "tech"}), ""},
+		{"expired", sign(jwt.SigningMethodHS256, []byte("secret"), jwt.StandardClaims{Subject: "tech", ExpiresAt: time.Now().Add(-time.Hour).Unix()}), ""},
+		{"no subject", sign(jwt.SigningMethodHS256, []byte("secret"), jwt.StandardClaims{}), ""},
+	}
+
+	for _, test := range tests {
+		subject, err := Subject(test.token)
+		if subject != test.subject {
+			t.Errorf("%s: expected subject %q, got %q", test.name, test.subject, subject)
+		}
+		if len(test.subject) == 0 && err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		}
+	}
+}
+
+func TestUninitialized(t *testing.T) {
+	if err := Authorize(Request{Permissions: []Permission{{Operation: Read}}}); err == nil {
+		t.Error("expected every request to be denied before Init")
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	initialized, disabled = true, true
+	defer func() { initialized, disabled = false, false }()
+
+	if err := Authorize(Request{Permissions: []Permission{{Operation: Power}}}); err != nil {
+		t.Errorf("expected control to be allowed while authorization is disabled: %s", err)
+	}
+
+	if err := Authorize(Request{Permissions: []Permission{{Operation: Admin}}}); err == nil {
+		t.Error("expected admin operations to be denied while authorization is disabled")
+	}
+}
+
+func TestUnknownFieldPermissions(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields map[string]interface{}
+		perms  []Permission
+	}{
+		{"known", map[string]interface{}{"Power": "on"}, nil},
+		{"room-wide", map[string]interface{}{"frozen": true}, []Permission{{Operation: Control}}},
+		{"device", map[string]interface{}{
+			"displays": []interface{}{map[string]interface{}{"name": "D1", "frozen": true}},
+			"screens":  []interface{}{map[string]interface{}{"name": "SCR1", "state": "down"}},
+		}, []Permission{{Device: "D1", Operation: Control}}},
+	}
+
+	for _, test := range tests {
+		perms := RequiredPermissions(base.PublicRoom{Fields: test.fields})
+		if len(perms) != len(test.perms) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.perms, perms)
+			continue
+		}
+
+		for i := range perms {
+			if perms[i] != test.perms[i] {
+				t.Errorf("%s: expected %v, got %v", test.name, test.perms, perms)
+			}
+		}
+	}
+}
+
+func TestInit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "authorization")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "policies.json")
+	err = ioutil.WriteFile(path, []byte(`{"policies": [{"subjects": ["*"], "operations": ["read"]}]}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		fails   bool
+		enabled bool
+	}{
+		{name: "nothing set allows everything", env: map[string]string{}},
+		{name: "policies without a key", env: map[string]string{"AUTHORIZATION_POLICY_PATH": path}, fails: true},
+		{name: "policies with a secret", env: map[string]string{"AUTHORIZATION_POLICY_PATH": path, "AUTHORIZATION_JWT_SECRET": "secret"}, enabled: true},
+		{name: "unreadable policies", env: map[string]string{"AUTHORIZATION_POLICY_PATH": path + ".missing", "AUTHORIZATION_JWT_SECRET": "secret"}, fails: true},
+	}
+
+	defer func() { initialized, disabled, policies, keyFunc = false, false, nil, nil }()
+
+	for _, test := range tests {
+		initialized, disabled, policies, keyFunc = false, false, nil, nil
+		for _, key := range []string{"AUTHORIZATION_POLICY_PATH", "AUTHORIZATION_JWT_SECRET", "AUTHORIZATION_JWT_KEY_PATH", "AUTHORIZATION_DISABLED"} {
+			os.Setenv(key, test.env[key])
+			defer os.Unsetenv(key)
+		}
+
+		err := Init()
+		if test.fails {
+			if err == nil {
+				t.Errorf("%s: expected Init to fail", test.name)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+
+		if Enabled() != test.enabled {
+			t.Errorf("%s: expected enabled to be %v", test.name, test.enabled)
+		}
+	}
+}
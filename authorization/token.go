@@ -0,0 +1,80 @@
+package authorization
+
+import (
+	"crypto/rsa"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+// keyFunc returns the key a bearer token's signature is checked with. It's nil if no key is configured.
+var keyFunc jwt.Keyfunc
+
+// loadKey reads the RSA public key in AUTHORIZATION_JWT_KEY_PATH, or the shared secret in AUTHORIZATION_JWT_SECRET.
+// Tokens must be signed with the matching algorithm; anything else, including unsigned tokens, is rejected.
+func loadKey() (jwt.Keyfunc, error) {
+	if path := os.Getenv("AUTHORIZATION_JWT_KEY_PATH"); len(path) > 0 {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := jwt.ParseRSAPublicKeyFromPEM(b)
+		if err != nil {
+			return nil, err
+		}
+
+		return rsaKey(key), nil
+	}
+
+	if secret := os.Getenv("AUTHORIZATION_JWT_SECRET"); len(secret) > 0 {
+		return hmacKey([]byte(secret)), nil
+	}
+
+	return nil, nil
+}
+
+func rsaKey(key *rsa.PublicKey) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+
+		return key, nil
+	}
+}
+
+func hmacKey(secret []byte) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+
+		return secret, nil
+	}
+}
+
+// Subject returns the subject of a bearer token, once its signature and expiry have been verified.
+func Subject(token string) (string, error) {
+	if keyFunc == nil {
+		return "", fmt.Errorf("no key is configured to verify bearer tokens")
+	}
+
+	var claims jwt.StandardClaims
+	t, err := jwt.ParseWithClaims(token, &claims, keyFunc)
+	if err != nil {
+		return "", err
+	}
+
+	if !t.Valid {
+		return "", fmt.Errorf("invalid token")
+	}
+
+	if len(claims.Subject) == 0 {
+		return "", fmt.Errorf("token has no subject")
+	}
+
+	return claims.Subject, nil
+}
@@ -1,6 +1,12 @@
 package base
 
 import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
 	ei "github.com/byuoitav/common/events"
 	"github.com/byuoitav/common/structs"
 )
@@ -17,6 +23,92 @@ type PublicRoom struct {
 	Volume            *int          `json:"volume,omitempty"`
 	Displays          []Display     `json:"displays,omitempty"`
 	AudioDevices      []AudioDevice `json:"audioDevices,omitempty"`
+	Screens           []Motorized   `json:"screens,omitempty"`
+	Lifts             []Motorized   `json:"lifts,omitempty"`
+	Shades            []Motorized   `json:"shades,omitempty"`
+	Locks             []Lock        `json:"locks,omitempty"`
+	Conflicts         []Conflict    `json:"conflicts,omitempty"`
+	Mismatches        []Mismatch    `json:"mismatches,omitempty"`
+	Errors            []StatusError `json:"errors,omitempty"`
+
+	//Fields is the JSON body the room was PUT with, including fields that only configured evaluators know about
+	Fields map[string]interface{} `json:"-"`
+}
+
+//DeviceLists are the lists of devices in a room's JSON body.
+var DeviceLists = []string{"displays", "audioDevices", "screens", "lifts", "shades"}
+
+//UnknownFields lists the fields of a JSON object that don't belong to v, a struct, like the extra fields a configured evaluator reads.
+//Like encoding/json, names are matched without regard to case.
+func UnknownFields(object map[string]interface{}, v interface{}) []string {
+	known := make(map[string]bool)
+	jsonFields(reflect.TypeOf(v), known)
+
+	var unknown []string
+	for key := range object {
+		if !known[strings.ToLower(key)] {
+			unknown = append(unknown, key)
+		}
+	}
+
+	sort.Strings(unknown)
+	return unknown
+}
+
+func jsonFields(t reflect.Type, known map[string]bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		tag := field.Tag.Get("json")
+		name := strings.Split(tag, ",")[0]
+
+		switch {
+		case tag == "-":
+		case field.Anonymous && len(name) == 0:
+			jsonFields(field.Type, known)
+		case len(name) > 0:
+			known[strings.ToLower(name)] = true
+		default:
+			known[strings.ToLower(field.Name)] = true
+		}
+	}
+}
+
+//StatusError is a query against a device that failed, so its fields in the room's state couldn't all be read.
+type StatusError struct {
+	Device  string `json:"device"`
+	Command string `json:"command,omitempty"`
+	Error   string `json:"error"`
+}
+
+//Mismatch is a field a device reported differently when it was read back than when it was set.
+type Mismatch struct {
+	Device  string `json:"device"`
+	Field   string `json:"field"`
+	Desired string `json:"desired"`
+	Actual  string `json:"actual"`
+}
+
+//Conflict is a pair of incompatible actions a request asked for on the same device, and which one was kept.
+//An empty Kept means both were dropped.
+type Conflict struct {
+	Device  string   `json:"device"`
+	Actions []string `json:"actions"`
+	Kept    string   `json:"kept,omitempty"`
+	Policy  string   `json:"policy"`
+}
+
+//Lock is a lease a requestor holds on a room, or on some of the devices in it.
+//An empty Devices list means the whole room is locked.
+type Lock struct {
+	Holder   string    `json:"holder"`
+	Devices  []string  `json:"devices,omitempty"`
+	Acquired time.Time `json:"acquired"`
+	Expires  time.Time `json:"expires"`
 }
 
 //Device is a struct for inheriting
@@ -29,14 +121,64 @@ type Device struct {
 //AudioDevice represents an audio device
 type AudioDevice struct {
 	Device
-	Muted  *bool `json:"muted,omitempty"`
-	Volume *int  `json:"volume,omitempty"`
+	Muted    *bool `json:"muted,omitempty"`
+	Volume   *int  `json:"volume,omitempty"`
+	Battery  *int  `json:"battery,omitempty"`
+	Charging *bool `json:"charging,omitempty"`
+	RFSignal *int  `json:"rfSignal,omitempty"`
+	Info     Info  `json:"info,omitempty"`
 }
 
 //Display represents a display
 type Display struct {
 	Device
 	Blanked *bool `json:"blanked,omitempty"`
+	Info    Info  `json:"info,omitempty"`
+}
+
+//Motorized represents a projection screen, projector lift, or shade
+//State is up, down, or stop, and Position is how far down it is, as a percentage
+type Motorized struct {
+	Name     string `json:"name,omitempty"`
+	State    string `json:"state,omitempty"`
+	Position *int   `json:"position,omitempty"`
+}
+
+//The roles that mark a device as a motorized screen, lift, or shade.
+const (
+	ScreenRole = "Screen"
+	LiftRole   = "Lift"
+	ShadeRole  = "Shade"
+)
+
+//ProjectsOnto reports whether a display shows its picture on a screen, or hangs from a lift:
+//one of the two has a port connecting it to the other.
+func ProjectsOnto(display, motorized structs.Device) bool {
+	for _, port := range motorized.Ports {
+		if strings.EqualFold(port.SourceDevice, display.Name) || strings.EqualFold(port.DestinationDevice, display.Name) {
+			return true
+		}
+	}
+
+	for _, port := range display.Ports {
+		if strings.EqualFold(port.SourceDevice, motorized.Name) || strings.EqualFold(port.DestinationDevice, motorized.Name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//Info is any extra telemetry a device reports, like lamp hours or firmware version, by key
+type Info map[string]interface{}
+
+//PublicDevice is the state of a single device, as returned (or put) by the device-level API
+type PublicDevice struct {
+	Device
+	Blanked *bool `json:"blanked,omitempty"`
+	Muted   *bool `json:"muted,omitempty"`
+	Volume  *int  `json:"volume,omitempty"`
+	Info    Info  `json:"info,omitempty"`
 }
 
 //ActionStructure is the internal struct we use to pass commands around once
@@ -52,14 +194,44 @@ type ActionStructure struct {
 	Overridden          bool               `json:"overridden"`
 	EventLog            []ei.EventInfo     `json:"events"`
 	Children            []*ActionStructure `json:"children"`
+	Join                *Join              `json:"-"`
 	Callback            func(StatusPackage, chan<- StatusPackage) error
 }
 
+//Join holds back an action that is the child of more than one other action, so it's only run once, after the last of them.
+type Join struct {
+	mutex   sync.Mutex
+	waiting int
+}
+
+//NewJoin returns a join that waits for parents actions.
+func NewJoin(parents int) *Join {
+	return &Join{waiting: parents}
+}
+
+//Add makes the join wait for more parents.
+func (j *Join) Add(parents int) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.waiting += parents
+}
+
+//Arrive is called by each parent once it's done. It returns true for the last one, which should run the action.
+func (j *Join) Arrive() bool {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.waiting--
+	return j.waiting == 0
+}
+
 // DestinationDevice represents the device that is being acted upon.
 type DestinationDevice struct {
 	structs.Device
 	AudioDevice bool `json:"audio"`
 	Display     bool `json:"video"`
+	Motorized   bool `json:"motorized"`
 }
 
 // StatusPackage contains the callback information for the action.
@@ -5,12 +5,16 @@ import (
 	"strings"
 	"time"
 
+	"github.com/byuoitav/av-api/sinks"
 	"github.com/byuoitav/common/events"
 )
 
 // EventNode is the event node used through the AV-API package to send events.
 var EventNode *events.EventNode
 
+// Sinks are everywhere events are sent. If none have been set, events only go to EventNode.
+var Sinks []sinks.Sink
+
 // PublishHealth is a wrapper function to publish an Event that is not an error.
 func PublishHealth(e events.Event) {
 	Publish(e, false)
@@ -37,13 +41,16 @@ func Publish(e events.Event, Error bool) error {
 
 	e.LocalEnvironment = len(os.Getenv("LOCAL_ENVIRONMENT")) > 0
 
-	if !Error {
-		EventNode.PublishEvent(events.APISuccess, e)
-	} else {
-		EventNode.PublishEvent(events.APIError, e)
+	header := events.APISuccess
+	if Error {
+		header = events.APIError
 	}
 
-	return err
+	if len(Sinks) == 0 {
+		return EventNode.PublishEvent(header, e)
+	}
+
+	return sinks.Publish(Sinks, header, e)
 }
 
 // SendEvent builds and then sends the Event to the event router.
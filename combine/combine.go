@@ -0,0 +1,534 @@
+package combine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/av-api/helpers"
+	"github.com/byuoitav/av-api/state"
+	"github.com/byuoitav/common/db"
+	"github.com/byuoitav/common/log"
+	"github.com/fatih/color"
+)
+
+// DefaultPath is where combined groups are kept if COMBINE_GROUPS_PATH is not set.
+const DefaultPath = "combined-rooms.json"
+
+// Group is a set of rooms in the same building that are acting as one, because the walls between them are open.
+// Requests to the primary room are carried out in every member room. Inputs maps the names of the primary's
+// sources to what each member room calls them, for members that don't use the same names.
+type Group struct {
+	Building string                       `json:"building"`
+	Primary  string                       `json:"primary"`
+	Members  []string                     `json:"members"`
+	Inputs   map[string]map[string]string `json:"inputs,omitempty"`
+}
+
+// RoomResult is what happened in one room of a group: the state it reported, or the error it failed with.
+type RoomResult struct {
+	State *base.PublicRoom `json:"state,omitempty"`
+	Error *helpers.Error   `json:"error,omitempty"`
+}
+
+// Rooms returns every room in the group, primary first.
+func (g Group) Rooms() []string {
+	return append([]string{g.Primary}, g.Members...)
+}
+
+// the groups that are currently combined, by building-room ID of each room in the group
+var groups = make(map[string]*Group)
+var mutex sync.RWMutex
+
+// GetPath returns where combined groups are kept, from COMBINE_GROUPS_PATH, or DefaultPath.
+func GetPath() string {
+	if path := os.Getenv("COMBINE_GROUPS_PATH"); len(path) > 0 {
+		return path
+	}
+
+	return DefaultPath
+}
+
+// Load restores the groups that were combined before the API last stopped.
+func Load() error {
+	b, err := ioutil.ReadFile(GetPath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var saved struct {
+		Groups []Group `json:"groups"`
+	}
+
+	err = json.Unmarshal(b, &saved)
+	if err != nil {
+		return fmt.Errorf("unable to parse %s: %s", GetPath(), err.Error())
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	for i := range saved.Groups {
+		group := &saved.Groups[i]
+		for _, room := range group.Rooms() {
+			groups[roomID(group.Building, room)] = group
+		}
+	}
+
+	log.L.Infof("%s", color.HiGreenString("[combine] restored %v combined groups from %s", len(saved.Groups), GetPath()))
+	return nil
+}
+
+//save writes every group to disk, so they survive a restart. Must be called with the mutex held.
+func save() {
+	saved := struct {
+		Groups []Group `json:"groups"`
+	}{Groups: []Group{}}
+
+	for id, group := range groups {
+		if roomID(group.Building, group.Primary) == id {
+			saved.Groups = append(saved.Groups, *group)
+		}
+	}
+
+	b, err := json.Marshal(saved)
+	if err != nil {
+		log.L.Warnf("[combine] unable to save combined groups: %s", err.Error())
+		return
+	}
+
+	tmp := GetPath() + ".tmp"
+	err = ioutil.WriteFile(tmp, b, 0644)
+	if err == nil {
+		err = os.Rename(tmp, GetPath())
+	}
+	if err != nil {
+		log.L.Warnf("[combine] unable to save combined groups: %s", err.Error())
+	}
+}
+
+// Combine joins partner rooms onto the primary room. If the primary is already combined, the partners replace its old members.
+// inputs maps, for each partner that needs it, the primary's source names to the partner's own.
+func Combine(building, primary string, partners []string, inputs map[string]map[string]string) (Group, error) {
+	if len(partners) == 0 {
+		return Group{}, errors.New("at least one partner room is required to combine")
+	}
+
+	group := &Group{Building: building, Primary: primary, Inputs: make(map[string]map[string]string)}
+
+	for partner, mapping := range inputs {
+		if !contains(partners, partner) {
+			return Group{}, fmt.Errorf("inputs are mapped for %s, which isn't being combined", partner)
+		}
+
+		group.Inputs[strings.ToUpper(partner)] = mapping
+	}
+
+	for _, partner := range partners {
+		if strings.EqualFold(partner, primary) {
+			return Group{}, fmt.Errorf("%s can't be combined with itself", primary)
+		}
+
+		//make sure the room actually exists before we start routing requests to it
+		_, err := db.GetDB().GetRoom(roomID(building, partner))
+		if err != nil {
+			return Group{}, helpers.Wrap(helpers.DatabaseError(err, helpers.UnknownRoom, roomID(building, partner)), "unable to get partner room "+partner)
+		}
+
+		group.Members = append(group.Members, partner)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	for _, room := range group.Rooms() {
+		if existing, ok := groups[roomID(building, room)]; ok && !strings.EqualFold(existing.Primary, primary) {
+			return Group{}, fmt.Errorf("%s is already combined with %s", room, existing.Primary)
+		}
+	}
+
+	//clear out the old version of this group, if there was one
+	if existing, ok := groups[roomID(building, primary)]; ok {
+		for _, room := range existing.Rooms() {
+			delete(groups, roomID(building, room))
+		}
+	}
+
+	for _, room := range group.Rooms() {
+		groups[roomID(building, room)] = group
+	}
+
+	save()
+
+	log.L.Infof("%s", color.HiGreenString("[combine] combined %s with %s in %s", primary, strings.Join(group.Members, ", "), building))
+	return *group, nil
+}
+
+// Separate splits up the group that the primary room leads.
+func Separate(building, primary string) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	group, ok := groups[roomID(building, primary)]
+	if !ok || !strings.EqualFold(group.Primary, primary) {
+		return fmt.Errorf("%s-%s is not the primary room of a combined group", building, primary)
+	}
+
+	for _, room := range group.Rooms() {
+		delete(groups, roomID(building, room))
+	}
+
+	save()
+
+	log.L.Infof("%s", color.HiGreenString("[combine] separated %s in %s", strings.Join(group.Rooms(), ", "), building))
+	return nil
+}
+
+// GetGroup returns the group a room belongs to, if it is combined.
+func GetGroup(building, room string) (Group, bool) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	group, ok := groups[roomID(building, room)]
+	if !ok {
+		return Group{}, false
+	}
+
+	return *group, true
+}
+
+// IsPrimary reports whether the room is leading a combined group.
+func IsPrimary(building, room string) (Group, bool) {
+	group, ok := GetGroup(building, room)
+	if !ok || !strings.EqualFold(group.Primary, room) {
+		return Group{}, false
+	}
+
+	return group, true
+}
+
+// Split divides a request made to the primary room into a request for each room in the group.
+// Room-wide settings go to every room, with the inputs renamed for members that call them something else.
+// Devices named "<room>-<device>" go to that member room, and all other devices belong to the primary.
+// The body the request was sent with is divided the same way.
+func Split(group Group, target base.PublicRoom) map[string]base.PublicRoom {
+	output := make(map[string]base.PublicRoom)
+
+	for _, room := range group.Rooms() {
+		split := target
+		split.Building = group.Building
+		split.Room = room
+		split.CurrentVideoInput = group.input(room, target.CurrentVideoInput)
+		split.CurrentAudioInput = group.input(room, target.CurrentAudioInput)
+		split.Displays = nil
+		split.AudioDevices = nil
+		split.Screens = nil
+		split.Lifts = nil
+		split.Shades = nil
+
+		output[room] = split
+	}
+
+	for _, display := range target.Displays {
+		room, name := group.owner(display.Name)
+		display.Name = name
+
+		split := output[room]
+		split.Displays = append(split.Displays, display)
+		output[room] = split
+	}
+
+	for _, audioDevice := range target.AudioDevices {
+		room, name := group.owner(audioDevice.Name)
+		audioDevice.Name = name
+
+		split := output[room]
+		split.AudioDevices = append(split.AudioDevices, audioDevice)
+		output[room] = split
+	}
+
+	for _, screen := range target.Screens {
+		room, name := group.owner(screen.Name)
+		screen.Name = name
+
+		split := output[room]
+		split.Screens = append(split.Screens, screen)
+		output[room] = split
+	}
+
+	for _, lift := range target.Lifts {
+		room, name := group.owner(lift.Name)
+		lift.Name = name
+
+		split := output[room]
+		split.Lifts = append(split.Lifts, lift)
+		output[room] = split
+	}
+
+	for _, shade := range target.Shades {
+		room, name := group.owner(shade.Name)
+		shade.Name = name
+
+		split := output[room]
+		split.Shades = append(split.Shades, shade)
+		output[room] = split
+	}
+
+	if target.Fields != nil {
+		for room, fields := range splitFields(group, target.Fields) {
+			split := output[room]
+			split.Fields = fields
+			output[room] = split
+		}
+	}
+
+	return output
+}
+
+// splitFields divides the body a request was sent with the same way, so configured evaluators in each room see their part of it.
+func splitFields(group Group, fields map[string]interface{}) map[string]map[string]interface{} {
+	output := make(map[string]map[string]interface{})
+
+	for _, room := range group.Rooms() {
+		split := make(map[string]interface{})
+		for key, value := range fields {
+			split[key] = value
+		}
+
+		for _, key := range []string{"currentVideoInput", "currentAudioInput"} {
+			if input, ok := fields[key].(string); ok {
+				split[key] = group.input(room, input)
+			}
+		}
+
+		for _, list := range base.DeviceLists {
+			delete(split, list)
+		}
+
+		output[room] = split
+	}
+
+	for _, list := range base.DeviceLists {
+		entries, _ := fields[list].([]interface{})
+		for _, entry := range entries {
+			device, ok := entry.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			name, _ := device["name"].(string)
+			room, name := group.owner(name)
+
+			renamed := make(map[string]interface{})
+			for key, value := range device {
+				renamed[key] = value
+			}
+			renamed["name"] = name
+
+			split := output[room]
+			entries, _ := split[list].([]interface{})
+			split[list] = append(entries, renamed)
+		}
+	}
+
+	return output
+}
+
+// input returns what a room in the group calls one of the primary's sources. Rooms without a mapping for it
+// are expected to call it the same thing.
+func (g Group) input(room, input string) string {
+	if len(input) == 0 {
+		return input
+	}
+
+	for name, mapped := range g.Inputs[strings.ToUpper(room)] {
+		if strings.EqualFold(name, input) {
+			return mapped
+		}
+	}
+
+	return input
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// owner finds the room a device in a request belongs to, and the name of the device in that room.
+func (g Group) owner(device string) (string, string) {
+	for _, member := range g.Members {
+		prefix := member + "-"
+		if len(device) > len(prefix) && strings.EqualFold(device[:len(prefix)], prefix) {
+			return member, device[len(prefix):]
+		}
+	}
+
+	return g.Primary, device
+}
+
+// SetRoomState carries out a request to the primary room in every room in the group, each with its own evaluators and reconciler.
+func SetRoomState(group Group, target base.PublicRoom, requestor string) (base.PublicRoom, error) {
+
+	log.L.Infof("%s", color.HiBlueString("[combine] setting state of %s in %s", strings.Join(group.Rooms(), ", "), group.Building))
+
+	requests := Split(group, target)
+
+	//rooms that have nothing to do are left alone
+	var rooms []string
+	for _, room := range group.Rooms() {
+		if hasChanges(requests[room]) {
+			rooms = append(rooms, room)
+		}
+	}
+
+	//if nothing changes anywhere, let the primary room report the error
+	if len(rooms) == 0 {
+		rooms = []string{group.Primary}
+	}
+
+	return forEachRoom(group, rooms, func(room string) (base.PublicRoom, error) {
+		return state.SetRoomState(requests[room], requestor)
+	})
+}
+
+func hasChanges(room base.PublicRoom) bool {
+	return len(room.CurrentVideoInput) > 0 ||
+		len(room.CurrentAudioInput) > 0 ||
+		len(room.Power) > 0 ||
+		room.Blanked != nil ||
+		room.Muted != nil ||
+		room.Volume != nil ||
+		len(room.Displays) > 0 ||
+		len(room.AudioDevices) > 0 ||
+		len(room.Screens) > 0 ||
+		len(room.Lifts) > 0 ||
+		len(room.Shades) > 0 ||
+		len(base.UnknownFields(room.Fields, room)) > 0
+}
+
+// GetRoomState returns the combined state of every room in the group.
+func GetRoomState(group Group) (base.PublicRoom, error) {
+	return forEachRoom(group, group.Rooms(), func(room string) (base.PublicRoom, error) {
+		return state.GetRoomState(group.Building, room)
+	})
+}
+
+// forEachRoom runs fn against the given rooms in the group at once, then merges the results into one room.
+// Devices in member rooms are renamed "<room>-<device>", so they can be told apart from the primary's.
+func forEachRoom(group Group, rooms []string, fn func(room string) (base.PublicRoom, error)) (base.PublicRoom, error) {
+	reports := make([]base.PublicRoom, len(rooms))
+	errs := make([]error, len(rooms))
+
+	var wg sync.WaitGroup
+	for i := range rooms {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			reports[i], errs[i] = fn(rooms[i])
+		}(i)
+	}
+	wg.Wait()
+
+	output := base.PublicRoom{
+		Building: group.Building,
+		Room:     group.Primary,
+	}
+
+	results := make(map[string]RoomResult)
+	var failed []string
+	for i, room := range rooms {
+		if errs[i] != nil {
+			log.L.Errorf("%s", color.HiRedString("[combine] %s failed: %s", room, errs[i].Error()))
+
+			e := helpers.ReturnError(errs[i])
+			results[room] = RoomResult{Error: &e}
+			failed = append(failed, room)
+			continue
+		}
+
+		results[room] = RoomResult{State: &reports[i]}
+
+		prefix := ""
+		if !strings.EqualFold(room, group.Primary) {
+			prefix = room + "-"
+		}
+
+		for _, display := range reports[i].Displays {
+			display.Name = prefix + display.Name
+			output.Displays = append(output.Displays, display)
+		}
+
+		for _, audioDevice := range reports[i].AudioDevices {
+			audioDevice.Name = prefix + audioDevice.Name
+			output.AudioDevices = append(output.AudioDevices, audioDevice)
+		}
+
+		for _, conflict := range reports[i].Conflicts {
+			conflict.Device = prefix + conflict.Device
+			output.Conflicts = append(output.Conflicts, conflict)
+		}
+
+		for _, mismatch := range reports[i].Mismatches {
+			mismatch.Device = prefix + mismatch.Device
+			output.Mismatches = append(output.Mismatches, mismatch)
+		}
+
+		for _, statusError := range reports[i].Errors {
+			statusError.Device = prefix + statusError.Device
+			output.Errors = append(output.Errors, statusError)
+		}
+
+		for _, screen := range reports[i].Screens {
+			screen.Name = prefix + screen.Name
+			output.Screens = append(output.Screens, screen)
+		}
+
+		for _, lift := range reports[i].Lifts {
+			lift.Name = prefix + lift.Name
+			output.Lifts = append(output.Lifts, lift)
+		}
+
+		for _, shade := range reports[i].Shades {
+			shade.Name = prefix + shade.Name
+			output.Shades = append(output.Shades, shade)
+		}
+	}
+
+	if len(failed) == 0 {
+		return output, nil
+	}
+
+	//the error keeps the kind of failure if every room failed the same way, so the request gets the status that fits it,
+	//and carries what happened in each room, including the ones that succeeded
+	code := results[failed[0]].Error.Code
+	for _, room := range failed {
+		if results[room].Error.Code != code {
+			code = helpers.Internal
+		}
+	}
+
+	message := fmt.Sprintf("%s failed", strings.Join(failed, ", "))
+	if len(failed) == 1 {
+		message = fmt.Sprintf("%s: %s", failed[0], results[failed[0]].Error.Message)
+	}
+
+	return output, helpers.NewError(code, "%s", message).WithDetail("rooms", results)
+}
+
+func roomID(building, room string) string {
+	return strings.ToUpper(fmt.Sprintf("%v-%v", building, room))
+}
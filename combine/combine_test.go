@@ -0,0 +1,225 @@
+package combine
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/av-api/helpers"
+)
+
+var testGroup = Group{
+	Building: "ITB",
+	Primary:  "1001",
+	Members:  []string{"1001A", "1001B"},
+	Inputs:   map[string]map[string]string{"1001B": {"HDMI1": "HDMI3"}},
+}
+
+func TestSplit(t *testing.T) {
+	muted := true
+	target := base.PublicRoom{
+		Building:          "ITB",
+		Room:              "1001",
+		CurrentVideoInput: "HDMI1",
+		Power:             "on",
+		Muted:             &muted,
+		Displays: []base.Display{
+			{Device: base.Device{Name: "D1"}},
+			{Device: base.Device{Name: "1001A-D1"}},
+			{Device: base.Device{Name: "1001a-D2"}},
+		},
+		AudioDevices: []base.AudioDevice{{Device: base.Device{Name: "1001B-MIC1"}}},
+		Screens:      []base.Motorized{{Name: "1001A-SCREEN1", State: "down"}},
+	}
+
+	tests := []struct {
+		room     string
+		input    string
+		displays []string
+		audio    []string
+		screens  []string
+	}{
+		{"1001", "HDMI1", []string{"D1"}, nil, nil},
+		{"1001A", "HDMI1", []string{"D1", "D2"}, nil, []string{"SCREEN1"}},
+		{"1001B", "HDMI3", nil, []string{"MIC1"}, nil},
+	}
+
+	requests := Split(testGroup, target)
+	if len(requests) != len(tests) {
+		t.Fatalf("expected a request for each of the %v rooms, got %v", len(tests), len(requests))
+	}
+
+	for _, test := range tests {
+		request := requests[test.room]
+
+		if request.Room != test.room || request.Building != "ITB" {
+			t.Errorf("%s: addressed to %s-%s", test.room, request.Building, request.Room)
+		}
+		if request.Power != "on" || request.Muted == nil || !*request.Muted {
+			t.Errorf("%s: expected the room-wide settings to be copied", test.room)
+		}
+		if request.CurrentVideoInput != test.input {
+			t.Errorf("%s: expected input %s, got %s", test.room, test.input, request.CurrentVideoInput)
+		}
+
+		var displays, audio, screens []string
+		for _, d := range request.Displays {
+			displays = append(displays, d.Name)
+		}
+		for _, a := range request.AudioDevices {
+			audio = append(audio, a.Name)
+		}
+		for _, s := range request.Screens {
+			screens = append(screens, s.Name)
+		}
+
+		if !equal(displays, test.displays) || !equal(audio, test.audio) || !equal(screens, test.screens) {
+			t.Errorf("%s: got displays %v, audio devices %v, screens %v", test.room, displays, audio, screens)
+		}
+	}
+}
+
+func TestSplitFields(t *testing.T) {
+	target := base.PublicRoom{
+		Fields: map[string]interface{}{
+			"currentVideoInput": "HDMI1",
+			"frozen":            true,
+			"displays": []interface{}{
+				map[string]interface{}{"name": "D1", "frozen": true},
+				map[string]interface{}{"name": "1001B-D1", "frozen": false},
+			},
+		},
+	}
+
+	requests := Split(testGroup, target)
+
+	for _, room := range testGroup.Rooms() {
+		if requests[room].Fields["frozen"] != true {
+			t.Errorf("%s: expected the room-wide field to be copied", room)
+		}
+	}
+
+	if input := requests["1001B"].Fields["currentVideoInput"]; input != "HDMI3" {
+		t.Errorf("expected 1001B to get input HDMI3, got %v", input)
+	}
+
+	if _, ok := requests["1001A"].Fields["displays"]; ok {
+		t.Error("expected 1001A not to get any displays")
+	}
+
+	displays, _ := requests["1001B"].Fields["displays"].([]interface{})
+	if len(displays) != 1 {
+		t.Fatalf("expected 1001B to get one display, got %v", displays)
+	}
+
+	display := displays[0].(map[string]interface{})
+	if display["name"] != "D1" || display["frozen"] != false {
+		t.Errorf("expected 1001B to get D1, unfrozen, got %v", display)
+	}
+
+	if !hasChanges(base.PublicRoom{Fields: map[string]interface{}{"frozen": true}}) {
+		t.Error("expected a field only configured evaluators know about to count as a change")
+	}
+}
+
+func TestForEachRoom(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures map[string]error
+		code     helpers.Code
+		displays int
+	}{
+		{"all succeed", nil, "", 3},
+		{"one fails", map[string]error{"1001A": helpers.NewError(helpers.DeviceUnreachable, "D1 is unreachable")}, helpers.DeviceUnreachable, 2},
+		{"same failures", map[string]error{
+			"1001A": helpers.NewError(helpers.Timeout, "timed out"),
+			"1001B": helpers.NewError(helpers.Timeout, "timed out"),
+		}, helpers.Timeout, 1},
+		{"different failures", map[string]error{
+			"1001A": helpers.NewError(helpers.Timeout, "timed out"),
+			"1001B": errors.New("something else"),
+		}, helpers.Internal, 1},
+	}
+
+	for _, test := range tests {
+		output, err := forEachRoom(testGroup, testGroup.Rooms(), func(room string) (base.PublicRoom, error) {
+			if err, ok := test.failures[room]; ok {
+				return base.PublicRoom{}, err
+			}
+			return base.PublicRoom{Displays: []base.Display{{Device: base.Device{Name: "D1"}}}}, nil
+		})
+
+		if len(output.Displays) != test.displays {
+			t.Errorf("%s: expected the %v rooms that succeeded to be reported, got %v", test.name, test.displays, len(output.Displays))
+		}
+
+		if len(test.failures) == 0 {
+			if err != nil {
+				t.Errorf("%s: unexpected error %s", test.name, err)
+			}
+			continue
+		}
+
+		e, ok := err.(*helpers.Error)
+		if !ok {
+			t.Fatalf("%s: expected a typed error, got %v", test.name, err)
+		}
+		if e.Code != test.code {
+			t.Errorf("%s: expected code %s, got %s", test.name, test.code, e.Code)
+		}
+
+		results := e.Details["rooms"].(map[string]RoomResult)
+		for _, room := range testGroup.Rooms() {
+			_, failed := test.failures[room]
+			if failed != (results[room].Error != nil) || failed == (results[room].State != nil) {
+				t.Errorf("%s: wrong result for %s: %+v", test.name, room, results[room])
+			}
+		}
+	}
+}
+
+func TestPersistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "combine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.Setenv("COMBINE_GROUPS_PATH", filepath.Join(dir, "groups.json"))
+	defer os.Unsetenv("COMBINE_GROUPS_PATH")
+
+	group := testGroup
+	groups = make(map[string]*Group)
+	for _, room := range group.Rooms() {
+		groups[roomID(group.Building, room)] = &group
+	}
+	save()
+
+	groups = make(map[string]*Group)
+	if err := Load(); err != nil {
+		t.Fatalf("unable to load groups: %s", err)
+	}
+
+	restored, ok := IsPrimary("ITB", "1001")
+	if !ok || !equal(restored.Members, testGroup.Members) || restored.Inputs["1001B"]["HDMI1"] != "HDMI3" {
+		t.Errorf("expected the group to be restored, got %+v", restored)
+	}
+	if _, ok := GetGroup("ITB", "1001b"); !ok {
+		t.Error("expected member rooms to be restored")
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
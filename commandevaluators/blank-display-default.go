@@ -1,11 +1,11 @@
 package commandevaluators
 
 import (
-	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/av-api/helpers"
 	"github.com/byuoitav/common/db"
 	"github.com/byuoitav/common/events"
 	"github.com/byuoitav/common/log"
@@ -126,7 +126,7 @@ func (p *BlankDisplayDefault) Validate(action base.ActionStructure) (err error)
 	// Return an error if the BlankDisplay command doesn't exist or the command in question isn't a BlankDisplay command
 	if !ok || !strings.EqualFold(action.Action, "BlankDisplay") {
 		log.L.Errorf("[command_evaluators] ERROR. %s is an invalid command for %s", action.Action, action.Device.Name)
-		return errors.New(action.Action + " is an invalid command for" + action.Device.Name)
+		return helpers.NewError(helpers.ConfigError, "%s is an invalid command for%s", action.Action, action.Device.Name)
 	}
 
 	log.L.Info("[command_evaluators] Done.")
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/av-api/helpers"
 	"github.com/byuoitav/common/db"
 	ei "github.com/byuoitav/common/events"
 	"github.com/byuoitav/common/log"
@@ -133,6 +134,29 @@ func (p *ChangeAudioInputDSP) Evaluate(room base.PublicRoom, requestor string) (
 	return actions, len(actions), nil
 }
 
+// Validate fulfills the Fulfill requirement on the command interface
+func (p *ChangeAudioInputDSP) Validate(action base.ActionStructure) error {
+	log.L.Infof("[command_evaluators] Validating action for command %v", action.Action)
+
+	//the media input changes on the DSP, and the other audio devices are muted
+	valid := strings.EqualFold(action.Action, "ChangeInput") || strings.EqualFold(action.Action, "Mute")
+
+	ok, _ := CheckCommands(action.Device.Type.Commands, action.Action)
+	if !ok || !valid {
+		msg := fmt.Sprintf("[command_evaluators] ERROR. %s is an invalid command for %s", action.Action, action.Device.Name)
+		log.L.Error(msg)
+		return helpers.NewError(helpers.ConfigError, "%s", msg)
+	}
+
+	log.L.Info("[command_evaluators] Done.")
+	return nil
+}
+
+// GetIncompatibleCommands returns the list of commands that are incompatible with this device.
+func (p *ChangeAudioInputDSP) GetIncompatibleCommands() []string {
+	return nil
+}
+
 // GetDSPMediaInputAction determines the devices affected and actions needed for this command.
 func GetDSPMediaInputAction(room base.PublicRoom, eventInfo ei.EventInfo, input string, deviceSpecific bool, destination base.DestinationDevice) (base.ActionStructure, error) {
 
@@ -149,7 +173,7 @@ func GetDSPMediaInputAction(room base.PublicRoom, eventInfo ei.EventInfo, input
 	if len(dsp) != 1 {
 		errorMessage := "[command_evaluators] Invalid DSP configuration detected in room"
 		log.L.Info(errorMessage)
-		return base.ActionStructure{}, errors.New(errorMessage)
+		return base.ActionStructure{}, helpers.NewError(helpers.ConfigError, "%s", errorMessage)
 	}
 
 	//get switcher
@@ -164,7 +188,7 @@ func GetDSPMediaInputAction(room base.PublicRoom, eventInfo ei.EventInfo, input
 	if len(switchers) != 1 {
 		errorMessage := "[command_evaluators] Invalid video switch configuration detected in room"
 		log.L.Info(errorMessage)
-		return base.ActionStructure{}, errors.New(errorMessage)
+		return base.ActionStructure{}, helpers.NewError(helpers.ConfigError, "%s", errorMessage)
 	}
 
 	//get requested device
@@ -184,7 +208,7 @@ func GetDSPMediaInputAction(room base.PublicRoom, eventInfo ei.EventInfo, input
 
 			switcherPorts := strings.Split(port.ID, ":")
 			if len(switcherPorts) != 2 {
-				return base.ActionStructure{}, errors.New("[command_evaluators] Invalid video switcher port")
+				return base.ActionStructure{}, helpers.NewError(helpers.ConfigError, "[command_evaluators] Invalid video switcher port")
 			}
 
 			parameters := make(map[string]string)
@@ -210,6 +234,6 @@ func GetDSPMediaInputAction(room base.PublicRoom, eventInfo ei.EventInfo, input
 
 	}
 
-	return base.ActionStructure{}, errors.New("[command_evaluators] No port found for given input")
+	return base.ActionStructure{}, helpers.NewError(helpers.InvalidRequest, "[command_evaluators] No port found for given input")
 
 }
@@ -1,13 +1,13 @@
 package commandevaluators
 
 import (
-	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/byuoitav/common/log"
 
 	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/av-api/helpers"
 	"github.com/byuoitav/common/db"
 	"github.com/byuoitav/common/events"
 	"github.com/byuoitav/common/structs"
@@ -98,7 +98,7 @@ func (c *ChangeVideoInputVideoSwitcher) Evaluate(room base.PublicRoom, requestor
 		splitP := strings.Split(p, ":")
 
 		if len(splitP) != 2 {
-			return actionList, 0, errors.New("[command_evaluators] Invalid port for a video switcher")
+			return actionList, 0, helpers.NewError(helpers.ConfigError, "[command_evaluators] Invalid port for a video switcher")
 		}
 
 		action.Parameters["input"] = splitP[0]
@@ -118,7 +118,7 @@ func GetSwitcherAndCreateAction(room base.PublicRoom, device structs.Device, sel
 	}
 
 	if len(switcher) != 1 {
-		return base.ActionStructure{}, errors.New("[command_evaluators] Too many switchers/none available")
+		return base.ActionStructure{}, helpers.NewError(helpers.ConfigError, "[command_evaluators] Too many switchers/none available")
 	}
 
 	log.L.Infof("[commandevaluators] Evaluating device %s for a port connecting %s to %s", switcher[0].ID, selectedInput, device.ID)
@@ -165,7 +165,7 @@ func GetSwitcherAndCreateAction(room base.PublicRoom, device structs.Device, sel
 		}
 	}
 
-	return base.ActionStructure{}, errors.New("[command_evaluators] No switcher found with the matching port")
+	return base.ActionStructure{}, helpers.NewError(helpers.InvalidRequest, "[command_evaluators] No switcher found with the matching port")
 }
 
 //Validate veries that the action that was created has correct information.
@@ -179,7 +179,7 @@ func (c *ChangeVideoInputVideoSwitcher) Validate(action base.ActionStructure) er
 	if !ok || action.Action != "ChangeInput" {
 		msg := fmt.Sprintf("[command_evaluators] ERROR. %s is an invalid command for %s", action.Action, action.Device.Name)
 		log.L.Error(msg)
-		return errors.New(msg)
+		return helpers.NewError(helpers.ConfigError, "%s", msg)
 	}
 
 	log.L.Info("[commandevaluators] Done.")
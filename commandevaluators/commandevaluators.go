@@ -5,6 +5,8 @@ import (
 	"fmt"
 
 	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/av-api/registry"
+	se "github.com/byuoitav/av-api/statusevaluators"
 	"github.com/byuoitav/common/db"
 	"github.com/byuoitav/common/structs"
 )
@@ -74,25 +76,54 @@ func getKeyValueFromCommmand(action base.ActionStructure) []string {
 		return []string{"Muted", "true"}
 	case "UnMute":
 		return []string{"Muted", "false"}
+	case "Raise":
+		return []string{"state", "up"}
+	case "Lower":
+		return []string{"state", "down"}
+	case "Stop":
+		return []string{"state", "stop"}
+	case "SetPosition":
+		return []string{"position", action.Parameters["position"]}
 	}
 	return []string{}
 }
 
-//EVALUATORS is the soft singleton command map
-var EVALUATORS = map[string]CommandEvaluator{
-	"PowerOnDefault":                 &PowerOnDefault{},
-	"StandbyDefault":                 &StandbyDefault{},
-	"ChangeVideoInputDefault":        &ChangeVideoInputDefault{},
-	"ChangeAudioInputDefault":        &ChangeAudioInputDefault{},
-	"ChangeVideoInputVideoSwitcher":  &ChangeVideoInputVideoSwitcher{},
-	"BlankDisplayDefault":            &BlankDisplayDefault{},
-	"UnBlankDisplayDefault":          &UnBlankDisplayDefault{},
-	"MuteDefault":                    &MuteDefault{},
-	"UnMuteDefault":                  &UnMuteDefault{},
-	"SetVolumeDefault":               &SetVolumeDefault{},
-	"SetVolumeTecLite":               &SetVolumeTecLite{},
-	"MuteDSP":                        &MuteDSP{},
-	"UnmuteDSP":                      &UnMuteDSP{},
-	"SetVolumeDSP":                   &SetVolumeDSP{},
-	"ChangeVideoInputTieredSwitcher": &ChangeVideoInputTieredSwitchers{},
+//EVALUATORS is the soft singleton command map, filled in by Register
+var EVALUATORS = make(map[string]CommandEvaluator)
+
+// Register adds a command evaluator under key, paired with the status evaluator that reads the responses to its commands,
+// and the commands it sends. The registry reports a key that is registered twice.
+func Register(key string, evaluator CommandEvaluator, status string, commands ...string) {
+	registry.Register(registry.Entry{
+		Key:          key,
+		Kind:         registry.Command,
+		Status:       status,
+		Commands:     commands,
+		Incompatible: evaluator.GetIncompatibleCommands(),
+		Value:        evaluator,
+	})
+
+	if _, ok := EVALUATORS[key]; !ok {
+		EVALUATORS[key] = evaluator
+	}
+}
+
+func init() {
+	Register("PowerOnDefault", &PowerOnDefault{}, se.PowerDefaultEvaluator, "PowerOn")
+	Register("StandbyDefault", &StandbyDefault{}, se.PowerDefaultEvaluator, "Standby")
+	Register("ChangeVideoInputDefault", &ChangeVideoInputDefault{}, se.DefaultInputEvaluator, "ChangeInput")
+	Register("ChangeAudioInputDefault", &ChangeAudioInputDefault{}, se.DefaultInputEvaluator, "ChangeInput")
+	Register("ChangeVideoInputVideoSwitcher", &ChangeVideoInputVideoSwitcher{}, se.InputVideoSwitcherEvaluator, "ChangeInput")
+	Register("ChangeVideoInputTieredSwitcher", &ChangeVideoInputTieredSwitchers{}, se.InputVideoSwitcherEvaluator, "ChangeInput")
+	Register("ChangeAudioInputDSP", &ChangeAudioInputDSP{}, se.InputDSPEvaluator, "ChangeInput", "Mute")
+	Register("BlankDisplayDefault", &BlankDisplayDefault{}, se.BlankedDefaultEvaluator, "BlankDisplay")
+	Register("UnBlankDisplayDefault", &UnBlankDisplayDefault{}, se.BlankedDefaultEvaluator, "UnblankDisplay")
+	Register("MuteDefault", &MuteDefault{}, se.MutedDefaultEvaluator, "Mute")
+	Register("UnMuteDefault", &UnMuteDefault{}, se.MutedDefaultEvaluator, "UnMute")
+	Register("SetVolumeDefault", &SetVolumeDefault{}, se.VolumeDefaultEvaluator, "SetVolume")
+	Register("SetVolumeTecLite", &SetVolumeTecLite{}, se.VolumeDefaultEvaluator, "SetVolume")
+	Register("MuteDSP", &MuteDSP{}, se.MutedDSPEvaluator, "Mute")
+	Register("UnmuteDSP", &UnMuteDSP{}, se.MutedDSPEvaluator, "UnMute")
+	Register("SetVolumeDSP", &SetVolumeDSP{}, se.VolumeDSPEvaluator, "SetVolume")
+	Register("MotorizedDefault", &MotorizedDefault{}, se.MotorizedDefaultEvaluator, RaiseCommand, LowerCommand, StopCommand, SetPositionCommand)
 }
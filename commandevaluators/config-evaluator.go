@@ -0,0 +1,322 @@
+package commandevaluators
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/av-api/helpers"
+	"github.com/byuoitav/av-api/registry"
+	"github.com/byuoitav/common/db"
+	"github.com/byuoitav/common/events"
+	"github.com/byuoitav/common/log"
+	"github.com/byuoitav/common/structs"
+)
+
+// ConfigEvaluatorDefinition describes a command evaluator built from configuration instead of code.
+// When Field (a dotted path into the PUT body, e.g. "blanked") matches Value, Command is sent to the devices
+// with one of Roles: every such device in the room if the field is set room-wide, or just the devices
+// it is set on in the displays, audioDevices, screens, lifts, and shades lists. An empty Value matches anything.
+// Parameters are templates, filled in with the matched {{.Value}}, the {{.Device}}, {{.Building}} and {{.Room}}.
+// Status is the status evaluator used to read the response to the command.
+type ConfigEvaluatorDefinition struct {
+	Key          string            `json:"key"`
+	Field        string            `json:"field"`
+	Value        string            `json:"value,omitempty"`
+	Roles        []string          `json:"roles"`
+	Command      string            `json:"command"`
+	Parameters   map[string]string `json:"parameters,omitempty"`
+	Incompatible []string          `json:"incompatible,omitempty"`
+	Status       string            `json:"status"`
+}
+
+// ConfigEvaluator implements the CommandEvaluator struct from a ConfigEvaluatorDefinition.
+type ConfigEvaluator struct {
+	ConfigEvaluatorDefinition
+	parameters  map[string]*template.Template
+	description string
+}
+
+// ConfigFlag starts the key of an evaluator that's defined in a room's configuration, instead of in code.
+// The evaluator's description holds its definition, as JSON.
+const ConfigFlag = "CONFIG_"
+
+// the evaluators defined in room configurations, by key. Keys are shared by every room, so a definition
+// that's changed in one room replaces the old definition for all of them.
+var configured = make(map[string]*ConfigEvaluator)
+var configuredMutex sync.RWMutex
+
+// FromConfiguration builds the evaluator an entry in a room's configuration defines, or returns
+// the one already built from the same definition.
+func FromConfiguration(entry structs.Evaluator) (*ConfigEvaluator, error) {
+	configuredMutex.RLock()
+	evaluator, ok := configured[entry.CodeKey]
+	configuredMutex.RUnlock()
+
+	if ok && evaluator.description == entry.Description {
+		return evaluator, nil
+	}
+
+	var def ConfigEvaluatorDefinition
+	err := json.Unmarshal([]byte(entry.Description), &def)
+	if err != nil {
+		return nil, helpers.NewError(helpers.ConfigError, "the description of %s isn't a valid evaluator definition: %s", entry.CodeKey, err.Error())
+	}
+
+	def.Key = entry.CodeKey
+
+	evaluator, err = NewConfigEvaluator(def)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := registry.Get(registry.Status, def.Status); !ok {
+		return nil, helpers.NewError(helpers.ConfigError, "%s is read by %s, which isn't a status evaluator", def.Key, def.Status)
+	}
+
+	evaluator.description = entry.Description
+
+	configuredMutex.Lock()
+	configured[entry.CodeKey] = evaluator
+	configuredMutex.Unlock()
+
+	log.L.Infof("[command_evaluators] loaded %s from the room configuration", entry.CodeKey)
+	return evaluator, nil
+}
+
+// Lookup returns the command evaluator registered under key, or defined under it in a room's configuration.
+func Lookup(key string) CommandEvaluator {
+	if evaluator, ok := EVALUATORS[key]; ok {
+		return evaluator
+	}
+
+	configuredMutex.RLock()
+	defer configuredMutex.RUnlock()
+
+	if evaluator, ok := configured[key]; ok {
+		return evaluator
+	}
+
+	return nil
+}
+
+// NewConfigEvaluator checks a definition and builds its evaluator.
+func NewConfigEvaluator(def ConfigEvaluatorDefinition) (*ConfigEvaluator, error) {
+	switch {
+	case len(def.Key) == 0:
+		return nil, helpers.NewError(helpers.ConfigError, "an evaluator key is required")
+	case len(def.Field) == 0, len(def.Command) == 0, len(def.Status) == 0:
+		return nil, helpers.NewError(helpers.ConfigError, "%s needs a field, a command and a status evaluator", def.Key)
+	case len(def.Roles) == 0:
+		return nil, helpers.NewError(helpers.ConfigError, "%s needs at least one role", def.Key)
+	}
+
+	evaluator := &ConfigEvaluator{
+		ConfigEvaluatorDefinition: def,
+		parameters:                make(map[string]*template.Template),
+	}
+
+	for name, text := range def.Parameters {
+		t, err := template.New(name).Parse(text)
+		if err != nil {
+			return nil, helpers.NewError(helpers.ConfigError, "invalid template for parameter %s of %s: %s", name, def.Key, err.Error())
+		}
+
+		evaluator.parameters[name] = t
+	}
+
+	return evaluator, nil
+}
+
+// Evaluate fulfills the CommmandEvaluation evaluate requirement.
+func (c *ConfigEvaluator) Evaluate(room base.PublicRoom, requestor string) ([]base.ActionStructure, int, error) {
+
+	log.L.Infof("[command_evaluators] Evaluating for %s...", c.Key)
+
+	//the field path is matched against the PUT body the way it's written in JSON
+	body := room.Fields
+	if body == nil {
+		b, err := json.Marshal(room)
+		if err != nil {
+			return []base.ActionStructure{}, 0, err
+		}
+
+		err = json.Unmarshal(b, &body)
+		if err != nil {
+			return []base.ActionStructure{}, 0, err
+		}
+	}
+
+	var actions []base.ActionStructure
+
+	//device-specific requests come first, so they win over a room-wide one
+	for _, list := range base.DeviceLists {
+		entries, _ := body[list].([]interface{})
+		for _, entry := range entries {
+			fields, _ := entry.(map[string]interface{})
+
+			value, ok := c.match(fields)
+			if !ok {
+				continue
+			}
+
+			name, _ := fields["name"].(string)
+			device, err := db.GetDB().GetDevice(fmt.Sprintf("%v-%v-%v", room.Building, room.Room, name))
+			if err != nil {
+				return []base.ActionStructure{}, 0, err
+			}
+
+			if !c.hasRole(device) {
+				return []base.ActionStructure{}, 0, helpers.NewError(helpers.InvalidRequest, "%s can't be sent to %s, it doesn't have any of the roles %s", c.Command, device.Name, strings.Join(c.Roles, ", "))
+			}
+
+			if checkActionListForDevice(actions, device.ID, room.Room, room.Building) != -1 {
+				continue
+			}
+
+			action, err := c.action(device, value, room, requestor, true)
+			if err != nil {
+				return []base.ActionStructure{}, 0, err
+			}
+
+			actions = append(actions, action)
+		}
+	}
+
+	if value, ok := c.match(body); ok {
+		log.L.Infof("[command_evaluators] Room-wide %s request received. Retrieving all devices...", c.Field)
+
+		devices, err := db.GetDB().GetDevicesByRoom(fmt.Sprintf("%v-%v", room.Building, room.Room))
+		if err != nil {
+			return []base.ActionStructure{}, 0, err
+		}
+
+		for _, device := range devices {
+			if !c.hasRole(device) || checkActionListForDevice(actions, device.ID, room.Room, room.Building) != -1 {
+				continue
+			}
+
+			if ok, _ := CheckCommands(device.Type.Commands, c.Command); !ok {
+				continue
+			}
+
+			action, err := c.action(device, value, room, requestor, false)
+			if err != nil {
+				return []base.ActionStructure{}, 0, err
+			}
+
+			actions = append(actions, action)
+		}
+	}
+
+	log.L.Infof("[command_evaluators] %v actions generated.", len(actions))
+	log.L.Info("[command_evaluators] Evaluation complete.")
+
+	return actions, len(actions), nil
+}
+
+// match finds the field in a JSON object, and reports whether it's set to the value we're looking for.
+func (c *ConfigEvaluator) match(object map[string]interface{}) (string, bool) {
+	var current interface{} = object
+	for _, key := range strings.Split(c.Field, ".") {
+		fields, ok := current.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+
+		current, ok = fields[key]
+		if !ok || current == nil {
+			return "", false
+		}
+	}
+
+	value := fmt.Sprintf("%v", current)
+	if len(c.Value) > 0 && !strings.EqualFold(value, c.Value) {
+		return "", false
+	}
+
+	return value, true
+}
+
+func (c *ConfigEvaluator) hasRole(device structs.Device) bool {
+	for _, role := range c.Roles {
+		if structs.HasRole(device, role) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *ConfigEvaluator) action(device structs.Device, value string, room base.PublicRoom, requestor string, deviceSpecific bool) (base.ActionStructure, error) {
+	data := struct {
+		Value    string
+		Device   structs.Device
+		Building string
+		Room     string
+	}{value, device, room.Building, room.Room}
+
+	parameters := make(map[string]string)
+	for name, t := range c.parameters {
+		var buf bytes.Buffer
+		err := t.Execute(&buf, data)
+		if err != nil {
+			return base.ActionStructure{}, helpers.NewError(helpers.ConfigError, "unable to fill in parameter %s of %s: %s", name, c.Key, err.Error())
+		}
+
+		parameters[name] = buf.String()
+	}
+
+	field := c.Field
+	if index := strings.LastIndex(field, "."); index >= 0 {
+		field = field[index+1:]
+	}
+
+	log.L.Infof("[command_evaluators] Adding device %+v", device.Name)
+
+	return base.ActionStructure{
+		Action:              c.Command,
+		GeneratingEvaluator: c.Key,
+		Device:              device,
+		DestinationDevice: base.DestinationDevice{
+			Device:      device,
+			AudioDevice: structs.HasRole(device, "AudioOut"),
+			Display:     structs.HasRole(device, "VideoOut"),
+			Motorized:   structs.HasRole(device, base.ScreenRole) || structs.HasRole(device, base.LiftRole) || structs.HasRole(device, base.ShadeRole),
+		},
+		Parameters:     parameters,
+		DeviceSpecific: deviceSpecific,
+		EventLog: []events.EventInfo{{
+			Type:           events.CORESTATE,
+			EventCause:     events.USERINPUT,
+			Device:         device.Name,
+			EventInfoKey:   field,
+			EventInfoValue: value,
+			Requestor:      requestor,
+		}},
+	}, nil
+}
+
+// Validate fulfills the Fulfill requirement on the command interface
+func (c *ConfigEvaluator) Validate(action base.ActionStructure) error {
+	log.L.Infof("[command_evaluators] Validating action for command %v", action.Action)
+
+	ok, _ := CheckCommands(action.Device.Type.Commands, c.Command)
+	if !ok || !strings.EqualFold(action.Action, c.Command) {
+		msg := fmt.Sprintf("[command_evaluators] ERROR. %s is an invalid command for %s", action.Action, action.Device.Name)
+		log.L.Error(msg)
+		return helpers.NewError(helpers.ConfigError, "%s", msg)
+	}
+
+	log.L.Info("[command_evaluators] Done.")
+	return nil
+}
+
+// GetIncompatibleCommands keeps track of actions that are incompatable (on the same device)
+func (c *ConfigEvaluator) GetIncompatibleCommands() []string {
+	return c.Incompatible
+}
@@ -0,0 +1,251 @@
+package commandevaluators
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/av-api/helpers"
+	"github.com/byuoitav/common/db"
+	"github.com/byuoitav/common/events"
+	"github.com/byuoitav/common/log"
+	"github.com/byuoitav/common/structs"
+)
+
+// The commands that move screens, lifts, and shades.
+const (
+	RaiseCommand       = "Raise"
+	LowerCommand       = "Lower"
+	StopCommand        = "Stop"
+	SetPositionCommand = "SetPosition"
+)
+
+// MotorizedDefault implements the CommandEvaluator struct for screens, lifts, and shades.
+// Besides the ones asked for directly, it lowers screens and lifts when the room powers on, and raises them when it goes to standby.
+type MotorizedDefault struct {
+}
+
+// Evaluate fulfills the CommmandEvaluation evaluate requirement.
+func (m *MotorizedDefault) Evaluate(room base.PublicRoom, requestor string) (actions []base.ActionStructure, count int, err error) {
+
+	log.L.Info("[command_evaluators] Evaluating for motorized devices...")
+
+	//the devices asked for by name aren't touched by the interlock
+	requested := make(map[string]bool)
+
+	lists := []struct {
+		role    string
+		devices []base.Motorized
+	}{
+		{base.ScreenRole, room.Screens},
+		{base.LiftRole, room.Lifts},
+		{base.ShadeRole, room.Shades},
+	}
+
+	for _, list := range lists {
+		for _, motorized := range list.devices {
+			deviceID := fmt.Sprintf("%v-%v-%v", room.Building, room.Room, motorized.Name)
+			device, err := db.GetDB().GetDevice(deviceID)
+			if err != nil {
+				return []base.ActionStructure{}, 0, err
+			}
+
+			if !structs.HasRole(device, list.role) {
+				return []base.ActionStructure{}, 0, helpers.NewError(helpers.InvalidRequest, "%s is not a %s", device.Name, strings.ToLower(list.role))
+			}
+
+			action, err := motorizedAction(device, motorized, requestor)
+			if err != nil {
+				return []base.ActionStructure{}, 0, err
+			}
+
+			if action != nil {
+				requested[device.ID] = true
+				actions = append(actions, *action)
+			}
+		}
+	}
+
+	interlocked, err := m.interlock(room, requested, requestor)
+	if err != nil {
+		return []base.ActionStructure{}, 0, err
+	}
+	actions = append(actions, interlocked...)
+
+	log.L.Infof("[command_evaluators] %v actions generated.", len(actions))
+	log.L.Info("[command_evaluators] Evaluation complete.")
+
+	return actions, len(actions), nil
+}
+
+//motorizedAction builds the action that moves a device to the requested state or position.
+func motorizedAction(device structs.Device, motorized base.Motorized, requestor string) (*base.ActionStructure, error) {
+	if len(motorized.State) > 0 && motorized.Position != nil {
+		return nil, helpers.NewError(helpers.InvalidRequest, "%s can't be given both a state and a position", device.Name)
+	}
+
+	eventInfo := events.EventInfo{
+		Type:       events.CORESTATE,
+		EventCause: events.USERINPUT,
+		Device:     device.Name,
+		Requestor:  requestor,
+	}
+
+	action := base.ActionStructure{
+		Device:              device,
+		DestinationDevice:   base.DestinationDevice{Device: device, Motorized: true},
+		GeneratingEvaluator: "MotorizedDefault",
+		DeviceSpecific:      true,
+	}
+
+	switch {
+	case motorized.Position != nil:
+		if *motorized.Position < 0 || *motorized.Position > 100 {
+			return nil, helpers.NewError(helpers.InvalidRequest, "%v is not a valid position for %s, it must be between 0 and 100", *motorized.Position, device.Name)
+		}
+
+		action.Action = SetPositionCommand
+		action.Parameters = map[string]string{"position": strconv.Itoa(*motorized.Position)}
+		eventInfo.EventInfoKey = "position"
+		eventInfo.EventInfoValue = strconv.Itoa(*motorized.Position)
+
+	case strings.EqualFold(motorized.State, "up"):
+		action.Action = RaiseCommand
+	case strings.EqualFold(motorized.State, "down"):
+		action.Action = LowerCommand
+	case strings.EqualFold(motorized.State, "stop"):
+		action.Action = StopCommand
+	case len(motorized.State) == 0:
+		return nil, nil
+	default:
+		return nil, helpers.NewError(helpers.InvalidRequest, "%s is not a valid state for %s, it must be up, down, or stop", motorized.State, device.Name)
+	}
+
+	if len(eventInfo.EventInfoKey) == 0 {
+		eventInfo.EventInfoKey = "state"
+		eventInfo.EventInfoValue = strings.ToLower(motorized.State)
+	}
+
+	action.EventLog = []events.EventInfo{eventInfo}
+	return &action, nil
+}
+
+//interlock lowers the screens and lifts used by displays that are powering on, and raises the ones used by displays going to standby.
+//A display uses a screen or lift if one of them has a port connected to the other.
+//The reconciler makes sure the displays wait for the lowering, and the raising waits for standby.
+func (m *MotorizedDefault) interlock(room base.PublicRoom, requested map[string]bool, requestor string) ([]base.ActionStructure, error) {
+
+	//the power each display is being set to, by name. a room-wide setting applies to every display
+	power := make(map[string]string)
+	for _, display := range room.Displays {
+		if len(display.Power) > 0 {
+			power[display.Name] = strings.ToLower(display.Power)
+		}
+	}
+
+	if len(power) == 0 && len(room.Power) == 0 {
+		return nil, nil
+	}
+
+	roomID := fmt.Sprintf("%v-%v", room.Building, room.Room)
+	devices, err := db.GetDB().GetDevicesByRoom(roomID)
+	if err != nil {
+		return nil, helpers.DatabaseError(err, helpers.UnknownRoom, roomID)
+	}
+
+	var displays []structs.Device
+	for _, device := range devices {
+		if !structs.HasRole(device, "VideoOut") {
+			continue
+		}
+
+		if _, ok := power[device.Name]; !ok && len(room.Power) > 0 {
+			power[device.Name] = strings.ToLower(room.Power)
+		}
+		displays = append(displays, device)
+	}
+
+	var actions []base.ActionStructure
+	for _, device := range devices {
+		if requested[device.ID] || !(structs.HasRole(device, base.ScreenRole) || structs.HasRole(device, base.LiftRole)) {
+			continue
+		}
+
+		//it comes down if any of its displays are powering on, and only goes up once all of them are going to standby
+		var command, state string
+		linked, standingBy := 0, 0
+		for _, display := range displays {
+			if !base.ProjectsOnto(display, device) {
+				continue
+			}
+
+			linked++
+			switch power[display.Name] {
+			case "on":
+				command, state = LowerCommand, "down"
+			case "standby":
+				standingBy++
+			}
+		}
+
+		if len(command) == 0 && linked > 0 && standingBy == linked {
+			command, state = RaiseCommand, "up"
+		}
+
+		if len(command) == 0 {
+			continue
+		}
+
+		if ok, _ := CheckCommands(device.Type.Commands, command); !ok {
+			continue
+		}
+
+		log.L.Infof("[command_evaluators] Interlocking %s with display power, moving it %s", device.Name, state)
+
+		actions = append(actions, base.ActionStructure{
+			Action:              command,
+			Device:              device,
+			DestinationDevice:   base.DestinationDevice{Device: device, Motorized: true},
+			GeneratingEvaluator: "MotorizedDefault",
+			DeviceSpecific:      false,
+			EventLog: []events.EventInfo{{
+				Type:           events.CORESTATE,
+				EventCause:     events.USERINPUT,
+				Device:         device.Name,
+				EventInfoKey:   "state",
+				EventInfoValue: state,
+				Requestor:      requestor,
+			}},
+		})
+	}
+
+	return actions, nil
+}
+
+// Validate fulfills the Fulfill requirement on the command interface
+func (m *MotorizedDefault) Validate(action base.ActionStructure) error {
+	log.L.Infof("[command_evaluators] Validating action for command %v", action.Action)
+
+	switch action.Action {
+	case RaiseCommand, LowerCommand, StopCommand, SetPositionCommand:
+	default:
+		return helpers.NewError(helpers.ConfigError, "%s is not a motorized command", action.Action)
+	}
+
+	ok, _ := CheckCommands(action.Device.Type.Commands, action.Action)
+	if !ok {
+		msg := fmt.Sprintf("[command_evaluators] ERROR. %s is an invalid command for %s", action.Action, action.Device.Name)
+		log.L.Error(msg)
+		return helpers.NewError(helpers.ConfigError, "%s", msg)
+	}
+
+	log.L.Info("[command_evaluators] Done.")
+	return nil
+}
+
+// GetIncompatibleCommands keeps track of actions that are incompatable (on the same device)
+//a device only gets one motorized action per request, so there is nothing to reconcile
+func (m *MotorizedDefault) GetIncompatibleCommands() []string {
+	return []string{}
+}
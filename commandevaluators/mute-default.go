@@ -1,13 +1,13 @@
 package commandevaluators
 
 import (
-	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/byuoitav/common/log"
 
 	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/av-api/helpers"
 	"github.com/byuoitav/common/db"
 	"github.com/byuoitav/common/events"
 	"github.com/byuoitav/common/structs"
@@ -120,7 +120,7 @@ func (p *MuteDefault) Validate(action base.ActionStructure) error {
 	if !ok || !strings.EqualFold(action.Action, "Mute") {
 		msg := fmt.Sprintf("[command_evaluators] ERROR. %s is an invalid command for %s", action.Action, action.Device.Name)
 		log.L.Error(msg)
-		return errors.New(msg)
+		return helpers.NewError(helpers.ConfigError, "%s", msg)
 	}
 
 	log.L.Info("[command_evaluators] Done.")
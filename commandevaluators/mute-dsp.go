@@ -18,6 +18,7 @@ import (
 	"github.com/byuoitav/common/log"
 
 	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/av-api/helpers"
 	"github.com/byuoitav/common/db"
 	ei "github.com/byuoitav/common/events"
 	"github.com/byuoitav/common/structs"
@@ -103,7 +104,7 @@ func (p *MuteDSP) Evaluate(room base.PublicRoom, requestor string) ([]base.Actio
 			} else { //bad device
 				errorMessage := "[command_evaluators] Cannot set volume of device " + device.Name
 				log.L.Error(errorMessage)
-				return []base.ActionStructure{}, 0, errors.New(errorMessage)
+				return []base.ActionStructure{}, 0, helpers.NewError(helpers.InvalidRequest, "%s", errorMessage)
 			}
 		}
 	}
@@ -145,7 +146,7 @@ func GetGeneralMuteRequestActionsDSP(room base.PublicRoom, eventInfo ei.EventInf
 	if len(dsp) != 1 {
 		errorMessage := "[command_evaluators] Invalid number of DSP devices found in room: " + room.Room + " in building " + room.Building
 		log.L.Error(errorMessage)
-		return []base.ActionStructure{}, errors.New(errorMessage)
+		return []base.ActionStructure{}, helpers.NewError(helpers.ConfigError, "%s", errorMessage)
 	}
 
 	dspActions, err := GetDSPMediaMuteAction(dsp[0], room, eventInfo, false)
@@ -202,7 +203,7 @@ func GetMicMuteAction(mic structs.Device, room base.PublicRoom, eventInfo ei.Eve
 	if len(dsps) != 1 {
 		errorMessage := "[command_evaluators] Invalid DSP configuration detected."
 		log.L.Error(errorMessage)
-		return base.ActionStructure{}, errors.New(errorMessage)
+		return base.ActionStructure{}, helpers.NewError(helpers.ConfigError, "%s", errorMessage)
 	}
 
 	dsp := dsps[0]
@@ -227,7 +228,7 @@ func GetMicMuteAction(mic structs.Device, room base.PublicRoom, eventInfo ei.Eve
 		}
 	}
 
-	return base.ActionStructure{}, errors.New("[command_evaluators] Could not find port for mic " + mic.Name)
+	return base.ActionStructure{}, helpers.NewError(helpers.ConfigError, "[command_evaluators] Could not find port for mic %s", mic.Name)
 }
 
 // GetDSPMediaMuteAction generates a list of actions based on information about the room and the DSP.
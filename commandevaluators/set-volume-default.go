@@ -1,13 +1,13 @@
 package commandevaluators
 
 import (
-	"errors"
 	"fmt"
 	"strconv"
 
 	"github.com/byuoitav/common/log"
 
 	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/av-api/helpers"
 	"github.com/byuoitav/common/db"
 	"github.com/byuoitav/common/events"
 	"github.com/byuoitav/common/structs"
@@ -135,7 +135,7 @@ func validateSetVolumeMaxMin(action base.ActionStructure, maximum int, minimum i
 	if level > maximum || level < minimum {
 		msg := fmt.Sprintf("[command_evaluators] ERROR. %v is an invalid volume level for %s", action.Parameters["level"], action.Device.Name)
 		log.L.Error(msg)
-		return errors.New(msg)
+		return helpers.NewError(helpers.InvalidRequest, "%s", msg)
 	}
 	return nil
 }
@@ -19,6 +19,7 @@ import (
 	"github.com/byuoitav/common/log"
 
 	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/av-api/helpers"
 	"github.com/byuoitav/common/db"
 	"github.com/byuoitav/common/structs"
 
@@ -102,7 +103,7 @@ func (p *SetVolumeDSP) Evaluate(room base.PublicRoom, requestor string) ([]base.
 				} else { //bad device
 					errorMessage := "[command_evaluators] Cannot set volume of device: " + device.Name + " in given context"
 					log.L.Error(errorMessage)
-					return []base.ActionStructure{}, 0, errors.New(errorMessage)
+					return []base.ActionStructure{}, 0, helpers.NewError(helpers.InvalidRequest, "%s", errorMessage)
 				}
 			}
 		}
@@ -132,7 +133,7 @@ func (p *SetVolumeDSP) Validate(action base.ActionStructure) (err error) {
 	if level > maximum || level < minimum {
 		msg := fmt.Sprintf("[command_evaluators] ERROR. %v is an invalid volume level for %s", action.Parameters["level"], action.Device.Name)
 		log.L.Error(msg)
-		return errors.New(msg)
+		return helpers.NewError(helpers.InvalidRequest, "%s", msg)
 	}
 
 	return
@@ -161,7 +162,7 @@ func GetGeneralVolumeRequestActionsDSP(room base.PublicRoom, eventInfo ei.EventI
 	if len(dsp) != 1 {
 		errorMessage := "[command_evaluators] Invalid DSP configuration detected in room."
 		log.L.Error(errorMessage)
-		return []base.ActionStructure{}, errors.New(errorMessage)
+		return []base.ActionStructure{}, helpers.NewError(helpers.ConfigError, "%s", errorMessage)
 	}
 
 	dspActions, err := GetDSPMediaVolumeAction(dsp[0], room, eventInfo, *room.Volume)
@@ -222,7 +223,7 @@ func GetMicVolumeAction(mic structs.Device, room base.PublicRoom, eventInfo ei.E
 	if len(dsps) != 1 {
 		errorMessage := "[command_evaluators] Invalid DSP configuration detected in room."
 		log.L.Error(errorMessage)
-		return base.ActionStructure{}, errors.New(errorMessage)
+		return base.ActionStructure{}, helpers.NewError(helpers.ConfigError, "%s", errorMessage)
 	}
 
 	dsp := dsps[0]
@@ -231,7 +232,7 @@ func GetMicVolumeAction(mic structs.Device, room base.PublicRoom, eventInfo ei.E
 	if volume < 0 || volume > 100 {
 		errorMessage := "[command_evaluators] Invalid volume parameter: " + strconv.Itoa(volume)
 		log.L.Error(errorMessage)
-		return base.ActionStructure{}, errors.New(errorMessage)
+		return base.ActionStructure{}, helpers.NewError(helpers.InvalidRequest, "%s", errorMessage)
 	}
 
 	for _, port := range dsp.Ports {
@@ -255,7 +256,7 @@ func GetMicVolumeAction(mic structs.Device, room base.PublicRoom, eventInfo ei.E
 		}
 	}
 
-	return base.ActionStructure{}, errors.New("[command_evaluators] Could not find port for mic " + mic.Name)
+	return base.ActionStructure{}, helpers.NewError(helpers.ConfigError, "[command_evaluators] Could not find port for mic %s", mic.Name)
 }
 
 // GetDSPMediaVolumeAction generates a list of actions based on the room, DSP, and event information.
@@ -1,12 +1,12 @@
 package commandevaluators
 
 import (
-	"fmt"
 	"strconv"
 
 	"github.com/byuoitav/common/log"
 
 	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/av-api/helpers"
 )
 
 // SetVolumeTecLite implements the CommandEvaluator struct.
@@ -31,7 +31,7 @@ func (*SetVolumeTecLite) Evaluate(room base.PublicRoom, requestor string) ([]bas
 	for i := range actions {
 		oldLevel, err := strconv.Atoi(actions[i].Parameters["level"])
 		if err != nil {
-			err = fmt.Errorf("[command_evaluators] Could not parse parameter 'level' for an integer: %s", err.Error())
+			err = helpers.NewError(helpers.InvalidRequest, "[command_evaluators] Could not parse parameter 'level' for an integer: %s", err.Error())
 			log.L.Errorf("%s", err.Error())
 			return actions, count, err
 		}
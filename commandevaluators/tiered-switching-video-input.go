@@ -1,13 +1,13 @@
 package commandevaluators
 
 import (
-	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/byuoitav/common/log"
 
 	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/av-api/helpers"
 	"github.com/byuoitav/av-api/inputgraph"
 	"github.com/byuoitav/av-api/statusevaluators"
 	"github.com/byuoitav/common/db"
@@ -58,7 +58,7 @@ func (c *ChangeVideoInputTieredSwitchers) Evaluate(room base.PublicRoom, request
 
 	//build the graph
 	if (len(room.CurrentVideoInput) > 0) && (len(room.CurrentVideoInput) > 0) && (room.CurrentVideoInput != room.CurrentAudioInput) {
-		return []base.ActionStructure{}, 0, errors.New("[command_evaluators] Cannot change room wide video and audio input with the same request")
+		return []base.ActionStructure{}, 0, helpers.NewError(helpers.InvalidRequest, "[command_evaluators] Cannot change room wide video and audio input with the same request")
 	}
 
 	//get all the devices from the room
@@ -150,7 +150,7 @@ func (c *ChangeVideoInputTieredSwitchers) Validate(action base.ActionStructure)
 	if !ok || action.Action != "ChangeInput" {
 		msg := fmt.Sprintf("[command_evaluators] ERROR. %s is an invalid command for %s", action.Action, action.Device.Name)
 		log.L.Error(msg)
-		return errors.New(msg)
+		return helpers.NewError(helpers.ConfigError, "%s", msg)
 	}
 
 	log.L.Info("[command_evaluators] Done.")
@@ -172,26 +172,26 @@ func (c *ChangeVideoInputTieredSwitchers) RoutePath(input, output string, graph
 	if inDev, ok = graph.DeviceMap[input]; !ok {
 		msg := fmt.Sprintf("[command_evaluators] Device %s is not included in the connection graph for this room.", input)
 		log.L.Errorf("%s", color.HiRedString("[error] %s", msg))
-		return []base.ActionStructure{}, errors.New(msg)
+		return []base.ActionStructure{}, helpers.NewError(helpers.InvalidRequest, "%s", msg)
 	}
 
 	if !inDev.Device.Type.Input {
 		msg := fmt.Sprintf("[command_evaluators] Device %v is not an input device in this room", input)
 		log.L.Errorf("%s", color.HiRedString("[error] %s", msg))
-		return []base.ActionStructure{}, errors.New(msg)
+		return []base.ActionStructure{}, helpers.NewError(helpers.InvalidRequest, "%s", msg)
 	}
 
 	//validate output
 	if outDev, ok = graph.DeviceMap[output]; !ok {
 		msg := fmt.Sprintf("[command_evaluators] Device %v is not included in the connection graph for this room.", output)
 		log.L.Errorf("%s", color.HiRedString("[error] %s", msg))
-		return []base.ActionStructure{}, errors.New(msg)
+		return []base.ActionStructure{}, helpers.NewError(helpers.InvalidRequest, "%s", msg)
 	}
 
 	if !outDev.Device.Type.Output {
 		msg := fmt.Sprintf("[command_evaluators] Device %v is not an input device in this room", output)
 		log.L.Errorf("%s", color.HiRedString("[error] %s", msg))
-		return []base.ActionStructure{}, errors.New(msg)
+		return []base.ActionStructure{}, helpers.NewError(helpers.InvalidRequest, "%s", msg)
 	}
 
 	//find path
@@ -206,7 +206,7 @@ func (c *ChangeVideoInputTieredSwitchers) RoutePath(input, output string, graph
 	if !ok {
 		msg := fmt.Sprintf("[command_evaluators] Cannot set input %v; no signal path from %v to %v", input, input, output)
 		log.L.Errorf("%s", color.HiRedString("[error] %s", msg))
-		return []base.ActionStructure{}, errors.New(msg)
+		return []base.ActionStructure{}, helpers.NewError(helpers.InvalidRequest, "%s", msg)
 	}
 	as, err := c.GenerateActionsFromPath(p, callbackEngine, requestor)
 	if err != nil {
@@ -230,13 +230,13 @@ func (c *ChangeVideoInputTieredSwitchers) ChangeAll(input string, devices []stru
 	if dev, ok = graph.DeviceMap[input]; !ok {
 		msg := fmt.Sprintf("[command_evaluators] Device %v is not included in the connection graph for this room.", input)
 		log.L.Errorf("%s", color.HiRedString("[error] %s", msg))
-		return []base.ActionStructure{}, 0, errors.New(msg)
+		return []base.ActionStructure{}, 0, helpers.NewError(helpers.InvalidRequest, "%s", msg)
 	}
 
 	if !dev.Device.Type.Input {
 		msg := fmt.Sprintf("[command_evaluators] Device %v is not an input device in this room", input)
 		log.L.Errorf("%s", color.HiRedString("[error] %s", msg))
-		return []base.ActionStructure{}, 0, errors.New(msg)
+		return []base.ActionStructure{}, 0, helpers.NewError(helpers.InvalidRequest, "%s", msg)
 	}
 
 	//ok we know it's in the room, check it's reachability in the graph
@@ -250,7 +250,7 @@ func (c *ChangeVideoInputTieredSwitchers) ChangeAll(input string, devices []stru
 			if !ok {
 				msg := fmt.Sprintf("[command_evaluators] Cannot set room wide input %v. There does not exist a signal path from %v to %v", input, input, d.Name)
 				log.L.Error(color.HiRedString(msg))
-				return []base.ActionStructure{}, 0, errors.New(msg)
+				return []base.ActionStructure{}, 0, helpers.NewError(helpers.InvalidRequest, "%s", msg)
 			}
 
 			//it's reachable, store the path and move on
@@ -327,7 +327,7 @@ func generateActionForNonSwitch(prev, cur inputgraph.Node, destination structs.D
 	if len(in) == 0 {
 		msg := fmt.Sprintf("[command_evaluators] There is no path from %v to %v. Check the port configuration", cur.ID, prev.ID)
 		color.HiRedString(msg)
-		return base.ActionStructure{}, errors.New(msg)
+		return base.ActionStructure{}, helpers.NewError(helpers.ConfigError, "%s", msg)
 	}
 
 	//we put the inX:outY in the format X:Y
@@ -390,7 +390,7 @@ func generateActionForSwitch(prev, cur, next inputgraph.Node, destination struct
 	if len(in) == 0 || len(out) == 0 {
 		msg := fmt.Sprintf("[command_evaluators] No path through %v from %v to %v. Check the port configuration", cur.ID, prev.ID, next.ID)
 		color.HiRedString(msg)
-		return base.ActionStructure{}, errors.New(msg)
+		return base.ActionStructure{}, helpers.NewError(helpers.ConfigError, "%s", msg)
 	}
 
 	//we put the inX:outY in the format X:Y
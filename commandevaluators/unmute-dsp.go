@@ -20,6 +20,7 @@ import (
 	"github.com/byuoitav/common/log"
 
 	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/av-api/helpers"
 	"github.com/byuoitav/common/db"
 	ei "github.com/byuoitav/common/events"
 	"github.com/byuoitav/common/structs"
@@ -96,7 +97,7 @@ func (p *UnMuteDSP) Evaluate(room base.PublicRoom, requestor string) ([]base.Act
 				} else { //bad device
 					errorMessage := "[command_evaluators] Cannot set volume of device " + device.Name
 					log.L.Error(errorMessage)
-					return []base.ActionStructure{}, 0, errors.New(errorMessage)
+					return []base.ActionStructure{}, 0, helpers.NewError(helpers.InvalidRequest, "%s", errorMessage)
 				}
 			}
 		}
@@ -138,7 +139,7 @@ func GetGeneralUnMuteRequestActionsDSP(room base.PublicRoom, eventInfo ei.EventI
 	if len(dsp) != 1 {
 		errorMessage := "[command_evaluators] Invalid number of DSP devices found in room: " + room.Room + " in building " + room.Building
 		log.L.Error(errorMessage)
-		return []base.ActionStructure{}, errors.New(errorMessage)
+		return []base.ActionStructure{}, helpers.NewError(helpers.ConfigError, "%s", errorMessage)
 	}
 
 	action, err := GetDSPMediaUnMuteAction(dsp[0], room, eventInfo, false)
@@ -198,7 +199,7 @@ func GetMicUnMuteAction(mic structs.Device, room base.PublicRoom, eventInfo ei.E
 	if len(dsps) != 1 {
 		errorMessage := "[command_evaluators] Invalid DSP configuration detected."
 		log.L.Error(errorMessage)
-		return base.ActionStructure{}, errors.New(errorMessage)
+		return base.ActionStructure{}, helpers.NewError(helpers.ConfigError, "%s", errorMessage)
 	}
 
 	dsp := dsps[0]
@@ -223,7 +224,7 @@ func GetMicUnMuteAction(mic structs.Device, room base.PublicRoom, eventInfo ei.E
 
 	}
 
-	return base.ActionStructure{}, errors.New("[command_evaluators] Couldn't find port configuration for mic " + mic.Name)
+	return base.ActionStructure{}, helpers.NewError(helpers.ConfigError, "[command_evaluators] Couldn't find port configuration for mic %s", mic.Name)
 }
 
 // GetDSPMediaUnMuteAction generates a list of actions based on the room, DSP, and event information.
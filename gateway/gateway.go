@@ -1,12 +1,15 @@
 package gateway
 
 import (
-	"errors"
 	"fmt"
+	"os"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/byuoitav/av-api/helpers"
 	"github.com/byuoitav/common/db"
 	"github.com/byuoitav/common/log"
 	"github.com/byuoitav/common/structs"
@@ -42,7 +45,7 @@ func SetGateway(url string, device structs.Device) (string, error) {
 		if len(vals) == 0 {
 			msg := fmt.Sprintf("[gateway-processing] Invalid path, could not parse path for gateway replacement %v", url)
 			log.L.Error(color.HiRedString(msg))
-			return "", errors.New(msg)
+			return "", helpers.NewError(helpers.ConfigError, "%s", msg).WithDetail("device", device.Name)
 		}
 
 		//now we go through and replace
@@ -63,19 +66,80 @@ func SetStatusGateway(url string, device structs.Device) (string, error) {
 	return SetGateway(url, device)
 }
 
-//finds the address of the device that controls the given device, including the port connecting the two
+//DefaultCacheTTL is how long a device's gateway is remembered if GATEWAY_CACHE_TTL isn't set.
+const DefaultCacheTTL = 10 * time.Minute
+
+//cachedGateway is the gateway that controls a device, and the port connecting the two.
+type cachedGateway struct {
+	gateway structs.Device
+	port    string
+	expires time.Time
+}
+
+//the gateway of each gated device we've looked up, by device ID
+var cache = make(map[string]cachedGateway)
+var cacheMutex sync.RWMutex
+
+//GetCacheTTL returns how long a device's gateway is remembered, from GATEWAY_CACHE_TTL, or DefaultCacheTTL.
+func GetCacheTTL() time.Duration {
+	ttl, err := time.ParseDuration(os.Getenv("GATEWAY_CACHE_TTL"))
+	if err != nil || ttl < 0 {
+		return DefaultCacheTTL
+	}
+
+	return ttl
+}
+
+//Prewarm looks up the gateways a gated device is controlled through, so the first request to it doesn't have to.
+//Devices that aren't gated have nothing to look up.
+func Prewarm(device structs.Device) error {
+	if !structs.HasRole(device, "GatedDevice") {
+		return nil
+	}
+
+	gateway, _, err := getDeviceGateway(device)
+	if err != nil {
+		return err
+	}
+
+	return Prewarm(gateway)
+}
+
+//finds the address of the device that controls the given device, including the port connecting the two.
+//Gateways are remembered for GATEWAY_CACHE_TTL, since every command to a gated device needs one.
 func getDeviceGateway(d structs.Device) (structs.Device, string, error) {
+	cacheMutex.RLock()
+	cached, ok := cache[d.ID]
+	cacheMutex.RUnlock()
+
+	if ok && time.Now().Before(cached.expires) {
+		return cached.gateway, cached.port, nil
+	}
+
+	gateway, port, err := lookupDeviceGateway(d)
+	if err != nil {
+		return gateway, port, err
+	}
+
+	cacheMutex.Lock()
+	cache[d.ID] = cachedGateway{gateway: gateway, port: port, expires: time.Now().Add(GetCacheTTL())}
+	cacheMutex.Unlock()
+
+	return gateway, port, nil
+}
+
+func lookupDeviceGateway(d structs.Device) (structs.Device, string, error) {
 
 	//get devices by building and room and role
 	roomID := d.GetDeviceRoomID()
 	devices, err := db.GetDB().GetDevicesByRoomAndRole(roomID, "Gateway")
 	if err != nil {
-		return structs.Device{}, "", err
+		return structs.Device{}, "", helpers.DatabaseError(err, helpers.UnknownRoom, roomID)
 	}
 
 	if len(devices) == 0 {
 		msg := fmt.Sprintf("[gateway-processing] No gateway devices found in room %s", roomID)
-		return structs.Device{}, "", errors.New(msg)
+		return structs.Device{}, "", helpers.NewError(helpers.ConfigError, "%s", msg).WithDetail("device", d.Name)
 	}
 
 	for _, device := range devices {
@@ -89,7 +153,7 @@ func getDeviceGateway(d structs.Device) (structs.Device, string, error) {
 		}
 	}
 
-	return structs.Device{}, "", errors.New("[gateway-processing] Gateway not found")
+	return structs.Device{}, "", helpers.NewError(helpers.ConfigError, "[gateway-processing] Gateway not found").WithDetail("device", d.Name)
 }
 
 func processPort(gateway structs.Device, port string) (string, error) {
@@ -115,7 +179,7 @@ func processPort(gateway structs.Device, port string) (string, error) {
 		//there was an error
 		msg := fmt.Sprintf("[gateway-processing] There was no command for the gateway device %v that corresponds to port %v", gateway.ID, port)
 		log.L.Error(color.HiRedString(msg))
-		return "", errors.New(msg)
+		return "", helpers.NewError(helpers.ConfigError, "%s", msg).WithDetail("gateway", gateway.ID)
 	}
 	//for now we assume that those numbered parameters are only valid for the endpoint, otherwise we run into port issues
 	path := command.Endpoint.Path
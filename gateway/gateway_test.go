@@ -0,0 +1,35 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+
+	"github.com/byuoitav/common/structs"
+)
+
+func TestCachedGateway(t *testing.T) {
+	device := structs.Device{ID: "ITB-1101-D1", Name: "D1", Roles: []structs.Role{{ID: "GatedDevice"}}}
+	gateway := structs.Device{ID: "ITB-1101-GW1", Name: "GW1"}
+
+	cacheMutex.Lock()
+	cache[device.ID] = cachedGateway{gateway: gateway, port: "IR1", expires: time.Now().Add(time.Minute)}
+	cacheMutex.Unlock()
+
+	//the database isn't needed for a device whose gateway is cached
+	found, port, err := getDeviceGateway(device)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if found.ID != gateway.ID || port != "IR1" {
+		t.Errorf("expected the cached gateway, got %s on port %s", found.ID, port)
+	}
+
+	if err := Prewarm(device); err != nil {
+		t.Errorf("expected a cached device to pre-warm: %s", err)
+	}
+
+	if err := Prewarm(structs.Device{ID: "ITB-1101-D2", Name: "D2"}); err != nil {
+		t.Errorf("expected a device that isn't gated to have nothing to pre-warm: %s", err)
+	}
+}
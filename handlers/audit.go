@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/byuoitav/av-api/audit"
+	"github.com/byuoitav/av-api/authorization"
+	"github.com/byuoitav/av-api/helpers"
+	"github.com/byuoitav/common/log"
+	"github.com/labstack/echo"
+)
+
+//defaultAuditLimit is the page size used when the request doesn't ask for one.
+const defaultAuditLimit = 50
+
+//maxAuditLimit is the largest page we hand back in one request.
+const maxAuditLimit = 500
+
+//GetAuditRecords pages through the room control requests recorded in the audit log.
+//Reading one room's records takes read access to that room; anything wider takes admin.
+func GetAuditRecords(context echo.Context) error {
+	filter := audit.Filter{
+		Building:  context.QueryParam("building"),
+		Room:      context.QueryParam("room"),
+		Requestor: context.QueryParam("requestor"),
+		Limit:     defaultAuditLimit,
+	}
+
+	operation := authorization.Admin
+	if len(filter.Building) > 0 && len(filter.Room) > 0 {
+		operation = authorization.Read
+	}
+
+	err := authorize(context, filter.Building, filter.Room, getRequestor(context), []authorization.Permission{{Operation: operation}})
+	if err != nil {
+		return context.JSON(helpers.Envelope(http.StatusForbidden, err))
+	}
+
+	if since := context.QueryParam("since"); len(since) > 0 {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return context.JSON(helpers.Envelope(http.StatusBadRequest, errors.New("since must be an RFC3339 timestamp")))
+		}
+		filter.Since = t
+	}
+
+	if offset := context.QueryParam("offset"); len(offset) > 0 {
+		o, err := strconv.Atoi(offset)
+		if err != nil || o < 0 {
+			return context.JSON(helpers.Envelope(http.StatusBadRequest, errors.New("offset must be a non-negative integer")))
+		}
+		filter.Offset = o
+	}
+
+	if limit := context.QueryParam("limit"); len(limit) > 0 {
+		l, err := strconv.Atoi(limit)
+		if err != nil || l < 1 || l > maxAuditLimit {
+			return context.JSON(helpers.Envelope(http.StatusBadRequest, errors.New("limit must be between 1 and 500")))
+		}
+		filter.Limit = l
+	}
+
+	page, err := audit.GetStore().Query(filter)
+	if err != nil {
+		log.L.Errorf("[handlers] unable to query the audit log: %s", err.Error())
+		return context.JSON(helpers.Envelope(http.StatusInternalServerError, err))
+	}
+
+	return context.JSON(http.StatusOK, page)
+}
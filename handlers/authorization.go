@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"net"
+	"os"
+	"strings"
+
+	"github.com/byuoitav/av-api/authorization"
+	"github.com/byuoitav/av-api/base"
+	ei "github.com/byuoitav/common/events"
+	"github.com/byuoitav/common/log"
+	"github.com/fatih/color"
+	"github.com/labstack/echo"
+)
+
+//getRequestor resolves the hostname of the caller, falling back to their IP address.
+func getRequestor(context echo.Context) string {
+	hn, err := net.LookupAddr(context.RealIP())
+	color.Set(color.FgYellow, color.Bold)
+	defer color.Unset()
+
+	if err != nil || len(hn) == 0 {
+		log.L.Debugf("REQUESTOR: %s", context.RealIP())
+		return context.RealIP()
+	} else if strings.Contains(hn[0], "localhost") {
+		log.L.Debugf("REQUESTOR: %s", os.Getenv("PI_HOSTNAME"))
+		return os.Getenv("PI_HOSTNAME")
+	}
+
+	log.L.Debugf("REQUESTOR: %s", hn[0])
+	return hn[0]
+}
+
+//getSubjects returns the identity of the caller: the subject of their bearer token, once its signature has been verified.
+//The requestor isn't used, since it comes from addresses and headers the caller controls.
+func getSubjects(context echo.Context) []string {
+	header := context.Request().Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return nil
+	}
+
+	subject, err := authorization.Subject(strings.TrimPrefix(header, "Bearer "))
+	if err != nil {
+		log.L.Warnf("[handlers] ignoring bearer token: %s", err.Error())
+		return nil
+	}
+
+	return []string{subject}
+}
+
+//getHolder returns who a lock is held by: the subject of the caller's verified bearer token, or else the address their
+//connection came from. Unlike the requestor, neither can be claimed through X-Forwarded-For or X-Real-IP.
+func getHolder(context echo.Context) string {
+	if subjects := getSubjects(context); len(subjects) > 0 {
+		return subjects[0]
+	}
+
+	addr := context.Request().RemoteAddr
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+
+	return host
+}
+
+//caller is who made a request, read out of its context once, so that it can be used from other goroutines.
+type caller struct {
+	requestor string
+	subjects  []string
+	holder    string
+	override  bool
+}
+
+//getCaller reads who made the request, and whether they asked to override locks.
+func getCaller(context echo.Context) caller {
+	return caller{
+		requestor: getRequestor(context),
+		subjects:  getSubjects(context),
+		holder:    getHolder(context),
+		override:  isOverride(context),
+	}
+}
+
+//authorize checks the caller against the authorization policies, and publishes an event if they are denied.
+func authorize(context echo.Context, building, room, requestor string, permissions []authorization.Permission) error {
+	return authorizeCaller(caller{requestor: requestor, subjects: getSubjects(context)}, building, room, permissions)
+}
+
+//authorizeCaller is authorize, for a caller that's already been read from the request.
+func authorizeCaller(c caller, building, room string, permissions []authorization.Permission) error {
+	requestor := c.requestor
+	req := authorization.Request{
+		Subjects:    c.subjects,
+		Building:    building,
+		Room:        room,
+		Permissions: permissions,
+	}
+
+	err := authorization.Authorize(req)
+	if err != nil {
+		log.L.Warnf("%s", color.HiYellowString("[handlers] authorization denied: %s", err.Error()))
+		base.SendEvent(
+			ei.ERROR,
+			ei.USERINPUT,
+			"",
+			room,
+			building,
+			"authorization-denied",
+			err.Error(),
+			requestor,
+			true)
+	}
+
+	return err
+}
@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo"
+)
+
+func TestGetHolderIgnoresHeaders(t *testing.T) {
+	req := httptest.NewRequest("POST", "/buildings/ITB/rooms/1101/lock", nil)
+	req.RemoteAddr = "10.5.34.20:51234"
+	req.Header.Set("X-Forwarded-For", "10.5.34.99")
+	req.Header.Set("X-Real-IP", "10.5.34.99")
+	req.Header.Set("Authorization", "Bearer not-a-token")
+
+	context := echo.New().NewContext(req, httptest.NewRecorder())
+	if holder := getHolder(context); holder != "10.5.34.20" {
+		t.Errorf("expected the lock to be held by the connection's address, got %s", holder)
+	}
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/av-api/combine"
+	"github.com/byuoitav/av-api/helpers"
+	"github.com/byuoitav/common/db"
+	"github.com/byuoitav/common/log"
+	"github.com/byuoitav/common/structs"
+	"github.com/fatih/color"
+	"github.com/labstack/echo"
+)
+
+//defaultBulkConcurrency is how many rooms are changed at once, if BULK_CONCURRENCY isn't set.
+const defaultBulkConcurrency = 5
+
+//roomResult is the outcome of a building-wide request in one room.
+type roomResult struct {
+	Room    string           `json:"room"`
+	Success bool             `json:"success"`
+	Code    int              `json:"code"`
+	Error   string           `json:"error,omitempty"`
+	Report  *base.PublicRoom `json:"report,omitempty"`
+}
+
+//buildingResult summarizes a building-wide request.
+type buildingResult struct {
+	Building  string       `json:"building"`
+	Succeeded int          `json:"succeeded"`
+	Failed    int          `json:"failed"`
+	Skipped   int          `json:"skipped"`
+	Results   []roomResult `json:"results"`
+}
+
+//SetBuildingState applies the same PublicRoom body to every room in a building, optionally filtered by
+//room designation and configuration, and reports the outcome in each room.
+func SetBuildingState(context echo.Context) error {
+	building := context.Param("building")
+	log.L.Infof("%s", color.HiGreenString("[handlers] putting building-wide changes to %s...", building))
+
+	target, err := readRoomBody(context)
+	if err != nil {
+		return context.JSON(helpers.Envelope(http.StatusBadRequest, err))
+	}
+
+	rooms, err := db.GetDB().GetRoomsByBuilding(building)
+	if err != nil {
+		return context.JSON(helpers.Envelope(http.StatusInternalServerError, err))
+	}
+
+	rooms = filterRooms(rooms, context.QueryParam("designation"), context.QueryParam("configuration"))
+	//the context isn't safe to use from the goroutines below
+	c := getCaller(context)
+
+	output := buildingResult{
+		Building: building,
+		Results:  make([]roomResult, len(rooms)),
+	}
+
+	//only let so many rooms be changed at once, so we don't flood the microservices
+	limit := make(chan struct{}, bulkConcurrency())
+	var wg sync.WaitGroup
+
+	for i := range rooms {
+		room := strings.TrimPrefix(rooms[i].ID, building+"-")
+		output.Results[i].Room = room
+
+		//rooms combined into another room in the building are taken care of by their primary room
+		if group, ok := combine.GetGroup(building, room); ok && !strings.EqualFold(group.Primary, room) && containsRoom(rooms, building, group.Primary) {
+			output.Results[i].Code = http.StatusOK
+			output.Results[i].Error = "combined with " + group.Primary
+			output.Skipped++
+			continue
+		}
+
+		wg.Add(1)
+		go func(result *roomResult) {
+			defer wg.Done()
+
+			limit <- struct{}{}
+			defer func() { <-limit }()
+
+			request := target
+			request.Building = building
+			request.Room = result.Room
+
+			response := applyRoomState(c, request)
+			result.Code = response.Code
+			result.Success = response.Code == http.StatusOK
+
+			switch body := response.Body.(type) {
+			case base.PublicRoom:
+				result.Report = &body
+			case helpers.Error:
+				result.Error = body.Message
+			}
+		}(&output.Results[i])
+	}
+
+	wg.Wait()
+
+	for _, result := range output.Results {
+		if result.Success {
+			output.Succeeded++
+		} else if result.Code != http.StatusOK {
+			output.Failed++
+		}
+	}
+
+	log.L.Infof("%s", color.HiGreenString("[handlers] building-wide changes to %s done: %v succeeded, %v failed, %v skipped", building, output.Succeeded, output.Failed, output.Skipped))
+
+	return context.JSON(http.StatusOK, output)
+}
+
+//filterRooms keeps the rooms with the given designation and configuration. An empty filter matches every room.
+func filterRooms(rooms []structs.Room, designation, configuration string) []structs.Room {
+	var output []structs.Room
+
+	for _, room := range rooms {
+		if len(designation) > 0 && !strings.EqualFold(room.Designation, designation) {
+			continue
+		}
+
+		if len(configuration) > 0 && !strings.EqualFold(room.Configuration.ID, configuration) {
+			continue
+		}
+
+		output = append(output, room)
+	}
+
+	return output
+}
+
+func containsRoom(rooms []structs.Room, building, room string) bool {
+	for _, r := range rooms {
+		if strings.EqualFold(r.ID, building+"-"+room) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func bulkConcurrency() int {
+	concurrency, err := strconv.Atoi(os.Getenv("BULK_CONCURRENCY"))
+	if err != nil || concurrency < 1 {
+		return defaultBulkConcurrency
+	}
+
+	return concurrency
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/byuoitav/av-api/authorization"
+	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/av-api/combine"
+	"github.com/byuoitav/av-api/helpers"
+	ei "github.com/byuoitav/common/events"
+	"github.com/labstack/echo"
+)
+
+//combineRequest is the body of a request to combine rooms. Inputs maps, for each partner room that needs it,
+//the primary room's source names to the partner's own.
+type combineRequest struct {
+	Rooms  []string                     `json:"rooms"`
+	Inputs map[string]map[string]string `json:"inputs,omitempty"`
+}
+
+//CombineRooms joins the partner rooms in the body onto the room in the path, which becomes the primary room.
+func CombineRooms(context echo.Context) error {
+	building, room := context.Param("building"), context.Param("room")
+
+	var body combineRequest
+	err := context.Bind(&body)
+	if err != nil {
+		return context.JSON(helpers.Envelope(http.StatusBadRequest, err))
+	}
+
+	requestor := getRequestor(context)
+
+	for _, r := range append([]string{room}, body.Rooms...) {
+		err = authorize(context, building, r, requestor, []authorization.Permission{{Operation: authorization.Control}})
+		if err != nil {
+			return context.JSON(helpers.Envelope(http.StatusForbidden, err))
+		}
+	}
+
+	group, err := combine.Combine(building, room, body.Rooms, body.Inputs)
+	if err != nil {
+		return context.JSON(helpers.Envelope(http.StatusBadRequest, err))
+	}
+
+	base.SendEvent(ei.USERACTION, ei.USERINPUT, "", room, building, "combined", strings.Join(group.Members, ","), requestor, false)
+
+	return context.JSON(http.StatusOK, group)
+}
+
+//SeparateRooms splits up the group the room in the path is the primary of.
+func SeparateRooms(context echo.Context) error {
+	building, room := context.Param("building"), context.Param("room")
+
+	group, ok := combine.IsPrimary(building, room)
+	if !ok {
+		return context.JSON(helpers.Envelope(http.StatusNotFound, errors.New("room is not the primary room of a combined group")))
+	}
+
+	requestor := getRequestor(context)
+
+	for _, r := range group.Rooms() {
+		err := authorize(context, building, r, requestor, []authorization.Permission{{Operation: authorization.Control}})
+		if err != nil {
+			return context.JSON(helpers.Envelope(http.StatusForbidden, err))
+		}
+	}
+
+	err := combine.Separate(building, room)
+	if err != nil {
+		return context.JSON(helpers.Envelope(http.StatusNotFound, err))
+	}
+
+	base.SendEvent(ei.USERACTION, ei.USERINPUT, "", room, building, "separated", strings.Join(group.Members, ","), requestor, false)
+
+	return context.JSON(http.StatusOK, group)
+}
+
+//GetCombinedRooms returns the group the room in the path belongs to.
+func GetCombinedRooms(context echo.Context) error {
+	building, room := context.Param("building"), context.Param("room")
+
+	err := authorize(context, building, room, getRequestor(context), []authorization.Permission{{Operation: authorization.Read}})
+	if err != nil {
+		return context.JSON(helpers.Envelope(http.StatusForbidden, err))
+	}
+
+	group, ok := combine.GetGroup(building, room)
+	if !ok {
+		return context.JSON(helpers.Envelope(http.StatusNotFound, errors.New("room is not combined")))
+	}
+
+	return context.JSON(http.StatusOK, group)
+}
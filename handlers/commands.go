@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/byuoitav/av-api/authorization"
+	"github.com/byuoitav/av-api/helpers"
+	"github.com/byuoitav/av-api/state"
+	"github.com/labstack/echo"
+)
+
+//ExecuteRawCommand sends one of a device's commands directly to its microservice, filling in the parameters from
+//the body, and returns whatever the microservice said. Only callers with the admin operation may use it.
+func ExecuteRawCommand(context echo.Context) error {
+	building, room, device, command := context.Param("building"), context.Param("room"), context.Param("device"), context.Param("commandID")
+
+	parameters := make(map[string]string)
+	if context.Request().ContentLength > 0 {
+		err := context.Bind(&parameters)
+		if err != nil {
+			return context.JSON(helpers.Envelope(http.StatusBadRequest, err))
+		}
+	}
+
+	c := getCaller(context)
+	requestor := c.requestor
+
+	err := authorizeCaller(c, building, room, []authorization.Permission{{Device: device, Operation: authorization.Admin}})
+	if err != nil {
+		return context.JSON(helpers.Envelope(http.StatusForbidden, err))
+	}
+
+	code, err := checkLocks(c, building, room, []authorization.Permission{{Device: device, Operation: authorization.Admin}})
+	if err != nil {
+		return context.JSON(helpers.Envelope(code, err))
+	}
+
+	response, err := state.ExecuteRawCommand(building, room, device, command, parameters, requestor)
+	if err != nil {
+		return context.JSON(helpers.Envelope(http.StatusBadGateway, err))
+	}
+
+	if len(response.ContentType) > 0 {
+		context.Response().Header().Set(echo.HeaderContentType, response.ContentType)
+	}
+	context.Response().WriteHeader(response.StatusCode)
+	_, err = context.Response().Write(response.Body)
+
+	return err
+}
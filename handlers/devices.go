@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/byuoitav/av-api/authorization"
+	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/av-api/helpers"
+	"github.com/byuoitav/av-api/state"
+	"github.com/byuoitav/common/log"
+	"github.com/fatih/color"
+	"github.com/labstack/echo"
+)
+
+//GetDeviceState returns the state of a single device, only querying that device.
+func GetDeviceState(context echo.Context) error {
+	building, room, device := context.Param("building"), context.Param("room"), context.Param("device")
+
+	err := authorize(context, building, room, getRequestor(context), []authorization.Permission{{Device: device, Operation: authorization.Read}})
+	if err != nil {
+		return context.JSON(helpers.Envelope(http.StatusForbidden, err))
+	}
+
+	status, err := state.GetDeviceState(building, room, device)
+	if err != nil {
+		return context.JSON(helpers.Envelope(http.StatusInternalServerError, err))
+	}
+
+	return context.JSON(http.StatusOK, status)
+}
+
+//SetDeviceState changes the state of a single device, and returns its new state.
+func SetDeviceState(context echo.Context) error {
+	building, room, device := context.Param("building"), context.Param("room"), context.Param("device")
+	log.L.Infof("%s", color.HiGreenString("[handlers] putting changes to %s...", device))
+
+	var target base.PublicDevice
+	err := context.Bind(&target)
+	if err != nil {
+		return context.JSON(helpers.Envelope(http.StatusBadRequest, err))
+	}
+
+	request, err := state.DeviceRequest(building, room, device, target)
+	if err != nil {
+		return context.JSON(helpers.Envelope(http.StatusBadRequest, err))
+	}
+
+	context.Response().Header().Set("Serialization-Policy", state.GetSerializationPolicy())
+
+	response := applyRoomState(getCaller(context), request)
+	if report, ok := response.Body.(base.PublicRoom); ok {
+		return context.JSON(response.Code, state.DeviceFromRoom(report, device))
+	}
+
+	return context.JSON(response.Code, response.Body)
+}
@@ -0,0 +1,13 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/byuoitav/av-api/registry"
+	"github.com/labstack/echo"
+)
+
+//GetEvaluators lists every registered command evaluator, status evaluator, reconciler and initializer.
+func GetEvaluators(context echo.Context) error {
+	return context.JSON(http.StatusOK, registry.List())
+}
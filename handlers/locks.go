@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/byuoitav/av-api/authorization"
+	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/av-api/helpers"
+	"github.com/byuoitav/av-api/locks"
+	ei "github.com/byuoitav/common/events"
+	"github.com/byuoitav/common/log"
+	"github.com/labstack/echo"
+)
+
+//lockRequest is the body of a request to lock a room.
+type lockRequest struct {
+	Devices []string `json:"devices,omitempty"`
+	TTL     int      `json:"ttl,omitempty"` // seconds
+}
+
+//LockRoom takes out, or renews, a lease on a room (or some of its devices) for the caller. The lease is held by getHolder,
+//so it can't be taken over by someone claiming to be the caller's host.
+func LockRoom(context echo.Context) error {
+	building, room := context.Param("building"), context.Param("room")
+
+	var body lockRequest
+	if context.Request().ContentLength > 0 {
+		err := context.Bind(&body)
+		if err != nil {
+			return context.JSON(helpers.Envelope(http.StatusBadRequest, err))
+		}
+	}
+
+	requestor := getRequestor(context)
+	holder := getHolder(context)
+	override := isOverride(context)
+
+	permissions := []authorization.Permission{}
+	if len(body.Devices) == 0 {
+		permissions = append(permissions, authorization.Permission{Operation: authorization.Lock})
+	}
+	for _, device := range body.Devices {
+		permissions = append(permissions, authorization.Permission{Device: device, Operation: authorization.Lock})
+	}
+	if override {
+		permissions = append(permissions, authorization.Permission{Operation: authorization.Admin})
+	}
+
+	err := authorize(context, building, room, requestor, permissions)
+	if err != nil {
+		return context.JSON(helpers.Envelope(http.StatusForbidden, err))
+	}
+
+	lock, err := locks.Acquire(building, room, holder, body.Devices, time.Duration(body.TTL)*time.Second, override)
+	if err != nil {
+		return context.JSON(helpers.Envelope(http.StatusLocked, err))
+	}
+
+	base.SendEvent(ei.USERACTION, ei.USERINPUT, strings.Join(lock.Devices, ","), room, building, "lock", lock.Holder, requestor, false)
+
+	return context.JSON(http.StatusOK, lock)
+}
+
+//UnlockRoom releases the caller's lease on a room. An admin can release everyone's lease with ?override=true.
+func UnlockRoom(context echo.Context) error {
+	building, room := context.Param("building"), context.Param("room")
+
+	requestor := getRequestor(context)
+	holder := getHolder(context)
+	override := isOverride(context)
+
+	permissions := []authorization.Permission{{Operation: authorization.Lock}}
+	if override {
+		permissions = append(permissions, authorization.Permission{Operation: authorization.Admin})
+	}
+
+	err := authorize(context, building, room, requestor, permissions)
+	if err != nil {
+		return context.JSON(helpers.Envelope(http.StatusForbidden, err))
+	}
+
+	err = locks.Release(building, room, holder, override)
+	if err != nil {
+		return context.JSON(helpers.Envelope(http.StatusNotFound, err))
+	}
+
+	base.SendEvent(ei.USERACTION, ei.USERINPUT, "", room, building, "unlock", holder, requestor, false)
+
+	return context.JSON(http.StatusOK, locks.Get(building, room))
+}
+
+//checkLocks makes sure no one else holds a lease on what the caller is about to change, renewing the caller's own lease.
+//An admin can ignore the lease with ?override=true.
+func checkLocks(c caller, building, room string, permissions []authorization.Permission) (int, error) {
+	if c.override {
+		err := authorizeCaller(c, building, room, []authorization.Permission{{Operation: authorization.Admin}})
+		if err != nil {
+			return http.StatusForbidden, err
+		}
+
+		log.L.Infof("[handlers] %s is overriding any locks on %s-%s", c.requestor, building, room)
+		return http.StatusOK, nil
+	}
+
+	err := locks.Check(building, room, c.holder, lockedDevices(permissions))
+	if err != nil {
+		log.L.Infof("[handlers] rejecting request from %s: %s", c.requestor, err.Error())
+		return http.StatusLocked, err
+	}
+
+	return http.StatusOK, nil
+}
+
+//lockedDevices lists the devices a set of permissions touches, or nothing if any of them is room-wide.
+func lockedDevices(permissions []authorization.Permission) []string {
+	var devices []string
+	for _, permission := range permissions {
+		if len(permission.Device) == 0 {
+			return nil
+		}
+
+		devices = append(devices, permission.Device)
+	}
+
+	return devices
+}
+
+func isOverride(context echo.Context) bool {
+	return strings.EqualFold(context.QueryParam("override"), "true")
+}
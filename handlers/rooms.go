@@ -1,14 +1,17 @@
 package handlers
 
 import (
+	"encoding/json"
 	"fmt"
-	"net"
+	"io/ioutil"
 	"net/http"
-	"os"
-	"strings"
 
+	"github.com/byuoitav/av-api/authorization"
 	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/av-api/combine"
 	"github.com/byuoitav/av-api/helpers"
+	"github.com/byuoitav/av-api/idempotency"
+	"github.com/byuoitav/av-api/locks"
 	"github.com/byuoitav/av-api/state"
 	"github.com/byuoitav/common/db"
 	"github.com/byuoitav/common/log"
@@ -20,20 +23,38 @@ func GetRoomState(context echo.Context) error {
 
 	building, room := context.Param("building"), context.Param("room")
 
-	status, err := state.GetRoomState(building, room)
+	err := authorize(context, building, room, getRequestor(context), []authorization.Permission{{Operation: authorization.Read}})
 	if err != nil {
-		return context.JSON(http.StatusBadRequest, err.Error())
+		return context.JSON(helpers.Envelope(http.StatusForbidden, err))
 	}
 
+	var status base.PublicRoom
+	if group, combined := combine.IsPrimary(building, room); combined {
+		status, err = combine.GetRoomState(group)
+	} else {
+		status, err = state.GetRoomState(building, room)
+	}
+	if err != nil {
+		return context.JSON(helpers.Envelope(http.StatusInternalServerError, err))
+	}
+
+	status.Locks = locks.Get(building, room)
+
 	return context.JSON(http.StatusOK, status)
 }
 
 //GetRoomByNameAndBuilding is almost identical to GetRoomByName
 func GetRoomByNameAndBuilding(context echo.Context) error {
+	err := authorize(context, context.Param("building"), context.Param("room"), getRequestor(context), []authorization.Permission{{Operation: authorization.Read}})
+	if err != nil {
+		return context.JSON(helpers.Envelope(http.StatusForbidden, err))
+	}
+
 	log.L.Info("Getting room...")
-	room, err := db.GetDB().GetRoom(fmt.Sprintf("%s-%s", context.Param("building"), context.Param("room")))
+	roomID := fmt.Sprintf("%s-%s", context.Param("building"), context.Param("room"))
+	room, err := db.GetDB().GetRoom(roomID)
 	if err != nil {
-		return context.JSON(http.StatusBadRequest, helpers.ReturnError(err))
+		return context.JSON(helpers.Envelope(http.StatusInternalServerError, helpers.DatabaseError(err, helpers.UnknownRoom, roomID)))
 	}
 	log.L.Info("Done.\n")
 	return context.JSON(http.StatusOK, room)
@@ -43,35 +64,113 @@ func SetRoomState(context echo.Context) error {
 	building, room := context.Param("building"), context.Param("room")
 	log.L.Infof("%s", color.HiGreenString("[handlers] putting room changes..."))
 
-	var roomInQuestion base.PublicRoom
-	err := context.Bind(&roomInQuestion)
+	roomInQuestion, err := readRoomBody(context)
 	if err != nil {
-		return context.JSON(http.StatusBadRequest, helpers.ReturnError(err))
+		return context.JSON(helpers.Envelope(http.StatusBadRequest, err))
 	}
 
 	roomInQuestion.Room = room
 	roomInQuestion.Building = building
-	var report base.PublicRoom
 
-	hn, err := net.LookupAddr(context.RealIP())
-	color.Set(color.FgYellow, color.Bold)
+	c := getCaller(context)
+	requestor := c.requestor
+
+	apply := func() idempotency.Response {
+		return applyRoomState(c, roomInQuestion)
+	}
+
+	context.Response().Header().Set("Serialization-Policy", state.GetSerializationPolicy())
+
+	key := context.Request().Header.Get("Idempotency-Key")
+	if len(key) == 0 {
+		response := apply()
+		return context.JSON(response.Code, response.Body)
+	}
+
+	//retries of the same request get the original response, rather than running it again
+	fingerprint, err := json.Marshal(roomInQuestion.Fields)
 	if err != nil {
-		log.L.Debugf("REQUESTOR: %s", context.RealIP())
-		color.Unset()
-		report, err = state.SetRoomState(roomInQuestion, context.RealIP())
-	} else if strings.Contains(hn[0], "localhost") {
-		log.L.Debugf("REQUESTOR: %s", os.Getenv("PI_HOSTNAME"))
-		color.Unset()
-		report, err = state.SetRoomState(roomInQuestion, os.Getenv("PI_HOSTNAME"))
-	} else {
-		log.L.Debugf("REQUESTOR: %s", hn[0])
-		color.Unset()
-		report, err = state.SetRoomState(roomInQuestion, hn[0])
+		return context.JSON(helpers.Envelope(http.StatusBadRequest, err))
 	}
 
+	response, replayed, err := idempotency.Do(fmt.Sprintf("%s-%s:%s:%s", building, room, requestor, key), string(fingerprint), apply)
 	if err != nil {
+		return context.JSON(helpers.Envelope(http.StatusUnprocessableEntity, err))
+	}
+
+	if replayed {
+		log.L.Infof("[handlers] replaying the response to %s for idempotency key %s", requestor, key)
+		context.Response().Header().Set("Idempotent-Replayed", "true")
+	}
+
+	return context.JSON(response.Code, response.Body)
+}
+
+//readRoomBody reads a PublicRoom from the request's body. The body is kept as it was sent too, in Fields,
+//since configured evaluators and authorization look for fields PublicRoom doesn't have.
+func readRoomBody(context echo.Context) (base.PublicRoom, error) {
+	var target base.PublicRoom
+
+	body, err := ioutil.ReadAll(context.Request().Body)
+	if err != nil {
+		return target, err
+	}
+
+	err = json.Unmarshal(body, &target)
+	if err != nil {
+		return target, err
+	}
+
+	err = json.Unmarshal(body, &target.Fields)
+	return target, err
+}
+
+//applyRoomState checks that the caller is allowed to make the change, and that no one else has the room locked,
+//then sets the state of the room (or every room in its group, if it's combined).
+func applyRoomState(c caller, target base.PublicRoom) idempotency.Response {
+	building, room, requestor := target.Building, target.Room, c.requestor
+
+	//a combined room carries out the request in each of its member rooms, so the requestor needs to be allowed in all of them
+	requests := map[string]base.PublicRoom{room: target}
+	group, combined := combine.IsPrimary(building, room)
+	if combined {
+		requests = combine.Split(group, target)
+	}
+
+	for r, request := range requests {
+		err := authorizeCaller(c, building, r, authorization.RequiredPermissions(request))
+		if err != nil {
+			return errorResponse(http.StatusForbidden, err)
+		}
+	}
+
+	for r, request := range requests {
+		permissions := authorization.RequiredPermissions(request)
+		if len(permissions) == 0 && r != room {
+			//nothing in this member room is being changed
+			continue
+		}
+
+		code, err := checkLocks(c, building, r, permissions)
+		if err != nil {
+			return errorResponse(code, err)
+		}
+	}
+
+	var report base.PublicRoom
+	var err error
+	if combined {
+		report, err = combine.SetRoomState(group, target, requestor)
+	} else {
+		report, err = state.SetRoomState(target, requestor)
+	}
+
+	if err == state.ErrRoomBusy || err == state.ErrSuperseded {
+		log.L.Warnf("Conflict: %s", err.Error())
+		return errorResponse(http.StatusConflict, err)
+	} else if err != nil {
 		log.L.Errorf("Error: %s", err.Error())
-		return context.JSON(http.StatusInternalServerError, helpers.ReturnError(err))
+		return errorResponse(http.StatusInternalServerError, err)
 	}
 
 	//hasError := helpers.CheckReport(report)
@@ -82,5 +181,11 @@ func SetRoomState(context echo.Context) error {
 	//	return context.JSON(http.StatusInternalServerError, report)
 	//}
 
-	return context.JSON(http.StatusOK, report)
+	return idempotency.Response{Code: http.StatusOK, Body: report}
+}
+
+//errorResponse is the response to a request that failed, with the status that fits the error.
+func errorResponse(fallback int, err error) idempotency.Response {
+	code, body := helpers.Envelope(fallback, err)
+	return idempotency.Response{Code: code, Body: body}
 }
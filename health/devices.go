@@ -0,0 +1,204 @@
+package health
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/byuoitav/av-api/base"
+	ce "github.com/byuoitav/av-api/commandevaluators"
+	"github.com/byuoitav/av-api/state"
+	"github.com/byuoitav/common/db"
+	ei "github.com/byuoitav/common/events"
+	"github.com/byuoitav/common/log"
+	"github.com/byuoitav/common/structs"
+	"github.com/fatih/color"
+)
+
+// The commands a device can be checked with, in order of preference.
+const (
+	HealthCommand = "STATUS_Health"
+	PowerCommand  = "STATUS_Power"
+)
+
+// DefaultCheckInterval is how often devices are checked on a local instance if DEVICE_HEALTH_INTERVAL isn't set.
+const DefaultCheckInterval = time.Minute
+
+// DeviceHealth is whether a device answered its last health check, and how quickly.
+type DeviceHealth struct {
+	Device    string     `json:"device"`
+	Reachable bool       `json:"reachable"`
+	Latency   string     `json:"latency,omitempty"`
+	LastSeen  *time.Time `json:"last-seen,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// the last result for each device we've checked, by device ID
+var lastResults = make(map[string]DeviceHealth)
+var lastResultsMutex sync.Mutex
+
+// GetCheckInterval returns how often devices are checked, from DEVICE_HEALTH_INTERVAL, or DefaultCheckInterval.
+func GetCheckInterval() time.Duration {
+	interval, err := time.ParseDuration(os.Getenv("DEVICE_HEALTH_INTERVAL"))
+	if err != nil || interval <= 0 {
+		return DefaultCheckInterval
+	}
+
+	return interval
+}
+
+// localRoom returns the ID of the room this Pi is in, from PI_HOSTNAME.
+func localRoom() (string, error) {
+	split := strings.Split(os.Getenv("PI_HOSTNAME"), "-")
+	if len(split) < 2 {
+		return "", errors.New("PI_HOSTNAME isn't in the form building-room-device")
+	}
+
+	return fmt.Sprintf("%v-%v", split[0], split[1]), nil
+}
+
+// CheckDevices queries every device in a room that has a health or power status command, all at once.
+// It doesn't change what's remembered about the devices; only the periodic check of this Pi's room does that.
+func CheckDevices(roomID string) ([]DeviceHealth, error) {
+	_, results, err := checkRoom(roomID)
+	return results, err
+}
+
+// checkRoom checks every device in a room, and returns the devices it checked with their results.
+func checkRoom(roomID string) ([]structs.Device, []DeviceHealth, error) {
+	devices, err := db.GetDB().GetDevicesByRoom(roomID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to get the devices in %s: %s", roomID, err.Error())
+	}
+
+	var checked []structs.Device
+	var commands []string
+	for _, device := range devices {
+		if has, _ := ce.CheckCommands(device.Type.Commands, HealthCommand); has {
+			checked = append(checked, device)
+			commands = append(commands, HealthCommand)
+		} else if has, _ := ce.CheckCommands(device.Type.Commands, PowerCommand); has {
+			checked = append(checked, device)
+			commands = append(commands, PowerCommand)
+		}
+	}
+
+	results := make([]DeviceHealth, len(checked))
+
+	var wg sync.WaitGroup
+	for i := range checked {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = checkDevice(checked[i], commands[i])
+		}(i)
+	}
+	wg.Wait()
+
+	return checked, results, nil
+}
+
+func checkDevice(device structs.Device, command string) DeviceHealth {
+	latency, err := state.ProbeDevice(device, command)
+
+	lastResultsMutex.Lock()
+	previous := lastResults[device.ID]
+	lastResultsMutex.Unlock()
+
+	result := DeviceHealth{
+		Device:    device.Name,
+		Reachable: err == nil,
+		LastSeen:  previous.LastSeen,
+	}
+
+	if err != nil {
+		result.Error = err.Error()
+	} else {
+		now := time.Now()
+		result.Latency = latency.String()
+		result.LastSeen = &now
+	}
+
+	return result
+}
+
+// recordResults remembers the results of a check of this Pi's room, publishes the devices whose reachability changed,
+// and updates the room's health.
+func recordResults(devices []structs.Device, results []DeviceHealth) {
+	lastResultsMutex.Lock()
+	for i, device := range devices {
+		previous, seenBefore := lastResults[device.ID]
+		lastResults[device.ID] = results[i]
+
+		if !seenBefore || previous.Reachable != results[i].Reachable {
+			publishReachability(device, results[i])
+		}
+	}
+	lastResultsMutex.Unlock()
+
+	unreachable := 0
+	for _, result := range results {
+		if !result.Reachable {
+			unreachable++
+		}
+	}
+
+	if unreachable > 0 {
+		SetStatus("Device Reachability", fmt.Sprintf("ERROR: %v of %v devices unreachable", unreachable, len(results)))
+	} else {
+		SetStatus("Device Reachability", "ok")
+	}
+}
+
+func publishReachability(device structs.Device, result DeviceHealth) {
+	value := "reachable"
+	if !result.Reachable {
+		value = "unreachable"
+		log.L.Warnf("%s", color.HiYellowString("[HealthCheck] %s is unreachable: %s", device.ID, result.Error))
+	} else {
+		log.L.Infof("%s", color.HiGreenString("[HealthCheck] %s is reachable", device.ID))
+	}
+
+	split := strings.Split(device.ID, "-")
+	if len(split) < 3 {
+		return
+	}
+
+	base.PublishHealth(ei.Event{
+		Event: ei.EventInfo{
+			Type:           ei.HEALTH,
+			EventCause:     ei.AUTOGENERATED,
+			Device:         device.Name,
+			EventInfoKey:   "reachability",
+			EventInfoValue: value,
+		},
+		Building: split[0],
+		Room:     split[1],
+	})
+}
+
+// StartDeviceChecks checks the devices in this Pi's room right away, then every DEVICE_HEALTH_INTERVAL. It never returns.
+func StartDeviceChecks() {
+	roomID, err := localRoom()
+	if err != nil {
+		log.L.Errorf("[HealthCheck] unable to start device checks: %s", err.Error())
+		return
+	}
+
+	log.L.Infof("%s", color.HiBlueString("[HealthCheck] checking the devices in %s every %v", roomID, GetCheckInterval()))
+
+	ticker := time.NewTicker(GetCheckInterval())
+	for {
+		devices, results, err := checkRoom(roomID)
+		if err != nil {
+			log.L.Warnf("[HealthCheck] %s", err.Error())
+		} else {
+			recordResults(devices, results)
+		}
+
+		<-ticker.C
+	}
+}
@@ -2,8 +2,11 @@ package health
 
 import (
 	"net/http"
+	"strconv"
+	"sync"
 
 	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/av-api/sinks"
 	"github.com/byuoitav/common/db"
 	"github.com/byuoitav/common/health"
 	"github.com/byuoitav/common/log"
@@ -12,6 +15,18 @@ import (
 
 //const version = "0.9.1"
 
+var statuses = make(map[string]string)
+var statusesMutex sync.RWMutex
+
+// SetStatus records the result of a check made outside of GetHealth, e.g. by a room initializer,
+// so it's included in every health report from then on.
+func SetStatus(name, status string) {
+	statusesMutex.Lock()
+	defer statusesMutex.Unlock()
+
+	statuses[name] = status
+}
+
 // GetHealth collects the health information about the microservice and formats it.
 func GetHealth() map[string]string {
 
@@ -31,6 +46,14 @@ func GetHealth() map[string]string {
 		healthReport["Configuration Database Microservice Connectivity"] = "ok"
 	}
 
+	healthReport["Event Queue Depth"] = strconv.Itoa(sinks.QueueDepth(base.Sinks))
+
+	statusesMutex.RLock()
+	for k, v := range statuses {
+		healthReport[k] = v
+	}
+	statusesMutex.RUnlock()
+
 	log.L.Info("[HealthCheck] Done. Report:")
 	for k, v := range healthReport {
 		log.L.Infof("%v: %v", k, v)
@@ -40,11 +63,34 @@ func GetHealth() map[string]string {
 	return healthReport
 }
 
-// Status gets the health as a status report and returns it.
+// Status gets the health as a status report and returns it. With deep=true, every device in the room
+// (this Pi's room, or the one in the room parameter as building-room) is checked too.
 func Status(context echo.Context) error {
-	report := GetHealth()
+	if context.QueryParam("deep") != "true" {
+		return context.JSON(http.StatusOK, GetHealth())
+	}
+
+	roomID := context.QueryParam("room")
+	if len(roomID) == 0 {
+		var err error
+		roomID, err = localRoom()
+		if err != nil {
+			return context.JSON(http.StatusBadRequest, "a room is required for a deep status check off of a Pi")
+		}
+	}
+
+	devices, err := CheckDevices(roomID)
+	if err != nil {
+		return context.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	deep := make(map[string]interface{})
+	for k, v := range GetHealth() {
+		deep[k] = v
+	}
+	deep["devices"] = devices
 
-	return context.JSON(http.StatusOK, report)
+	return context.JSON(http.StatusOK, deep)
 }
 
 // StartupCheckAndReport sends the health information on a successful start up.
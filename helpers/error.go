@@ -1,20 +1,181 @@
 package helpers
 
-// Error represents the API's method of returning errors to the user
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Code identifies what kind of error happened, so clients can handle it without parsing the message.
+type Code string
+
+// The kinds of error the API returns.
+const (
+	InvalidRequest    Code = "invalid-request"
+	UnknownRoom       Code = "unknown-room"
+	UnknownDevice     Code = "unknown-device"
+	ConfigError       Code = "config-error"
+	ConfigUnavailable Code = "config-unavailable"
+	DeviceUnreachable Code = "device-unreachable"
+	DeviceRejected    Code = "device-rejected"
+	Timeout           Code = "timeout"
+	Forbidden         Code = "forbidden"
+	NotFound          Code = "not-found"
+	Conflict          Code = "conflict"
+	Locked            Code = "locked"
+	Internal          Code = "internal"
+)
+
+var statuses = map[Code]int{
+	InvalidRequest:    http.StatusBadRequest,
+	UnknownRoom:       http.StatusNotFound,
+	UnknownDevice:     http.StatusNotFound,
+	ConfigError:       http.StatusInternalServerError,
+	ConfigUnavailable: http.StatusServiceUnavailable,
+	DeviceUnreachable: http.StatusBadGateway,
+	DeviceRejected:    http.StatusBadGateway,
+	Timeout:           http.StatusGatewayTimeout,
+	Forbidden:         http.StatusForbidden,
+	NotFound:          http.StatusNotFound,
+	Conflict:          http.StatusConflict,
+	Locked:            http.StatusLocked,
+	Internal:          http.StatusInternalServerError,
+}
+
+// the codes for errors that don't have one, by the status they're returned with
+var fallbackCodes = map[int]Code{
+	http.StatusBadRequest:          InvalidRequest,
+	http.StatusForbidden:           Forbidden,
+	http.StatusNotFound:            NotFound,
+	http.StatusConflict:            Conflict,
+	http.StatusUnprocessableEntity: InvalidRequest,
+	http.StatusLocked:              Locked,
+	http.StatusBadGateway:          DeviceUnreachable,
+	http.StatusServiceUnavailable:  ConfigUnavailable,
+	http.StatusGatewayTimeout:      Timeout,
+}
+
+// Error represents the API's method of returning errors to the user. It's also the error
+// returned from the packages under the handlers, so the handlers know what status to respond with.
 type Error struct {
-	Message string
+	Code    Code                   `json:"code"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// MarshalJSON fulfills the json.Marshaler interface. The message is also sent under Message, its key
+// before errors had codes, so clients that still read it keep working.
+func (e Error) MarshalJSON() ([]byte, error) {
+	type plain Error
+
+	return json.Marshal(struct {
+		plain
+		OldMessage string `json:"Message"`
+	}{plain(e), e.Message})
+}
+
+// NewError builds an error of the given kind.
+func NewError(code Code, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// WithDetail adds a detail to the error, and returns it.
+func (e *Error) WithDetail(key string, value interface{}) *Error {
+	if e.Details == nil {
+		e.Details = make(map[string]interface{})
+	}
+
+	e.Details[key] = value
+	return e
+}
+
+// Status returns the HTTP status an error of this kind is returned with.
+func (c Code) Status() int {
+	if status, ok := statuses[c]; ok {
+		return status
+	}
+
+	return http.StatusInternalServerError
+}
+
+// Wrap adds to the message of an error, keeping its kind and details if it has them.
+func Wrap(err error, message string) error {
+	if e, ok := err.(*Error); ok {
+		return &Error{Code: e.Code, Message: message + ": " + e.Message, Details: e.Details}
+	}
+
+	return fmt.Errorf("%s: %s", message, err.Error())
+}
+
+// DatabaseError turns an error from the configuration database into a typed error: missing is used
+// if the thing being looked up doesn't exist, and ConfigUnavailable if the database couldn't be reached.
+func DatabaseError(err error, missing Code, id string) *Error {
+	if IsNotFound(err) {
+		return NewError(missing, "%s doesn't exist", id).WithDetail("id", id)
+	}
+
+	return NewError(ConfigUnavailable, "unable to get %s from the configuration database: %s", id, err.Error()).WithDetail("id", id)
+}
+
+// the database client reports a missing document as a 404 from couch, with couch's "not_found" error
+var notFoundPattern = regexp.MustCompile(`(?i)\b(404|not[ _]found)\b`)
+
+// IsNotFound reports whether err from the configuration database means the document doesn't exist.
+// Errors that say so themselves, through a NotFound method or by being a NotFound type (e.g. couch.NotFound),
+// are trusted; anything else has to mention a 404 or not_found.
+func IsNotFound(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if e, ok := err.(interface {
+		NotFound() bool
+	}); ok {
+		return e.NotFound()
+	}
+
+	if strings.HasSuffix(fmt.Sprintf("%T", err), "NotFound") {
+		return true
+	}
+
+	return notFoundPattern.MatchString(err.Error())
 }
 
 // GenericError returns a generic error to the user
 func GenericError() Error {
-	errorResponse := Error{Message: "An error was encountered. Please contact your system administrator."}
+	errorResponse := Error{Code: Internal, Message: "An error was encountered. Please contact your system administrator."}
 
 	return errorResponse
 }
 
 // ReturnError returns JSON sharing the error message with the user
 func ReturnError(err error) Error {
-	errorResponse := Error{Message: err.Error()}
+	if e, ok := err.(*Error); ok {
+		return *e
+	}
+
+	errorResponse := Error{Code: Internal, Message: err.Error()}
 
 	return errorResponse
 }
+
+// Envelope returns the status and body to respond to a failed request with. A typed error
+// brings its own status; anything else gets the fallback status and a code to match.
+func Envelope(fallback int, err error) (int, Error) {
+	if e, ok := err.(*Error); ok {
+		return e.Code.Status(), *e
+	}
+
+	code, ok := fallbackCodes[fallback]
+	if !ok {
+		code = Internal
+	}
+
+	return fallback, Error{Code: code, Message: err.Error()}
+}
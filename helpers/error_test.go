@@ -0,0 +1,80 @@
+package helpers
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+type documentNotFound struct{}
+
+func (n *documentNotFound) Error() string {
+	return "document missing"
+}
+
+type missing bool
+
+func (m missing) Error() string {
+	return "404"
+}
+
+func (m missing) NotFound() bool {
+	return bool(m)
+}
+
+func TestIsNotFound(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		notFound bool
+	}{
+		{"couch not_found", errors.New(`unable to get room: {"error":"not_found","reason":"missing"}`), true},
+		{"404", errors.New("non-200 response code: 404"), true},
+		{"not found", errors.New("room ITB-1101 not found"), true},
+		{"NotFound type", &documentNotFound{}, true},
+		{"NotFound method", missing(false), false},
+		{"unreachable", errors.New("dial tcp 10.5.34.1:5984: connect: connection refused"), false},
+		{"missing field", errors.New("json: missing field in body"), false},
+		{"a port with 404 in it", errors.New("dial tcp 10.5.34.1:54040: i/o timeout"), false},
+		{"nothing", nil, false},
+	}
+
+	for _, test := range tests {
+		if IsNotFound(test.err) != test.notFound {
+			t.Errorf("%s: expected %v", test.name, test.notFound)
+		}
+	}
+}
+
+func TestDatabaseError(t *testing.T) {
+	err := DatabaseError(errors.New("not_found"), UnknownRoom, "ITB-1101")
+	if err.Code != UnknownRoom || err.Details["id"] != "ITB-1101" {
+		t.Errorf("expected an unknown room, got %+v", err)
+	}
+
+	err = DatabaseError(errors.New("connection refused"), UnknownRoom, "ITB-1101")
+	if err.Code != ConfigUnavailable {
+		t.Errorf("expected the database to be unavailable, got %+v", err)
+	}
+}
+
+func TestErrorJSON(t *testing.T) {
+	for _, e := range []interface{}{*NewError(Locked, "D1 is locked"), NewError(Locked, "D1 is locked")} {
+		b, err := json.Marshal(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var fields map[string]interface{}
+		json.Unmarshal(b, &fields)
+		if fields["message"] != "D1 is locked" || fields["Message"] != "D1 is locked" || fields["code"] != string(Locked) {
+			t.Errorf("expected both message keys and the code, got %s", b)
+		}
+
+		var decoded Error
+		err = json.Unmarshal(b, &decoded)
+		if err != nil || decoded.Message != "D1 is locked" || decoded.Code != Locked {
+			t.Errorf("expected the error to decode, got %+v, %v", decoded, err)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package idempotency
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/byuoitav/common/log"
+)
+
+// DefaultRetention is how long a response is kept if IDEMPOTENCY_RETENTION isn't set.
+const DefaultRetention = 10 * time.Minute
+
+// ErrKeyReused is returned when a key is sent again with a different request than the one it was first used for.
+var ErrKeyReused = errors.New("this idempotency key was already used for a different request")
+
+// Response is the stored result of the first request made with a key.
+type Response struct {
+	Code int
+	Body interface{}
+}
+
+type entry struct {
+	fingerprint string
+	done        chan struct{}
+	response    Response
+	stored      bool
+	expires     time.Time
+}
+
+var entries = make(map[string]*entry)
+var mutex sync.Mutex
+
+// GetRetention returns how long responses are kept, from IDEMPOTENCY_RETENTION, or DefaultRetention.
+func GetRetention() time.Duration {
+	retention, err := time.ParseDuration(os.Getenv("IDEMPOTENCY_RETENTION"))
+	if err != nil || retention <= 0 {
+		return DefaultRetention
+	}
+
+	return retention
+}
+
+// Completed reports whether a response is the final result of a request, and so can be given to a retry.
+// Successes and rejected requests are; conflicts, locks, and server errors might go away, so they aren't.
+func Completed(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusConflict, http.StatusLocked, http.StatusTooManyRequests:
+		return false
+	}
+
+	return code >= 200 && code < 500
+}
+
+// Do runs fn the first time it sees key, and stores what it returns if it's Completed. Later calls
+// with the same key get the stored response instead of running fn again; a call made while the first
+// one is still running waits for it to finish, and runs fn itself if the first call's response
+// wasn't stored (or fn panicked). The fingerprint identifies the request, so a key can't be reused
+// for a different one. replayed reports whether the response came from an earlier call.
+func Do(key, fingerprint string, fn func() Response) (response Response, replayed bool, err error) {
+	mutex.Lock()
+	expire()
+
+	e, ok := entries[key]
+	if ok {
+		mutex.Unlock()
+
+		if e.fingerprint != fingerprint {
+			return Response{}, false, ErrKeyReused
+		}
+
+		log.L.Infof("[idempotency] request with key %s has already been made, waiting for its response", key)
+		<-e.done
+
+		if !e.stored {
+			return Do(key, fingerprint, fn)
+		}
+
+		return e.response, true, nil
+	}
+
+	e = &entry{
+		fingerprint: fingerprint,
+		done:        make(chan struct{}),
+	}
+	entries[key] = e
+	mutex.Unlock()
+
+	//the entry only starts to age once it has a response to give out. if it doesn't get one,
+	//because the response can't be replayed or fn panicked, the key is free to be tried again
+	defer func() {
+		mutex.Lock()
+		if e.stored {
+			e.expires = time.Now().Add(GetRetention())
+		} else if entries[key] == e {
+			delete(entries, key)
+		}
+		mutex.Unlock()
+
+		close(e.done)
+	}()
+
+	e.response = fn()
+	e.stored = Completed(e.response.Code)
+
+	return e.response, false, nil
+}
+
+//expire removes the entries that are past the retention window. Must be called with the mutex held.
+func expire() {
+	now := time.Now()
+	for key, e := range entries {
+		if !e.expires.IsZero() && now.After(e.expires) {
+			delete(entries, key)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package idempotency
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestReplay(t *testing.T) {
+	tests := []struct {
+		name     string
+		first    int
+		replayed bool
+	}{
+		{"success", http.StatusOK, true},
+		{"bad request", http.StatusBadRequest, true},
+		{"forbidden", http.StatusForbidden, true},
+		{"conflict", http.StatusConflict, false},
+		{"locked", http.StatusLocked, false},
+		{"server error", http.StatusInternalServerError, false},
+		{"device unreachable", http.StatusBadGateway, false},
+	}
+
+	for _, test := range tests {
+		entries = make(map[string]*entry)
+		runs := 0
+		fn := func(code int) func() Response {
+			return func() Response {
+				runs++
+				return Response{Code: code}
+			}
+		}
+
+		Do("key", "body", fn(test.first))
+		response, replayed, err := Do("key", "body", fn(http.StatusOK))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", test.name, err)
+		}
+
+		if replayed != test.replayed {
+			t.Errorf("%s: expected replayed to be %v", test.name, test.replayed)
+		}
+
+		if test.replayed && (response.Code != test.first || runs != 1) {
+			t.Errorf("%s: expected the first response to be replayed, got %v after %v runs", test.name, response.Code, runs)
+		}
+		if !test.replayed && (response.Code != http.StatusOK || runs != 2) {
+			t.Errorf("%s: expected the request to run again, got %v after %v runs", test.name, response.Code, runs)
+		}
+	}
+}
+
+func TestKeyReused(t *testing.T) {
+	entries = make(map[string]*entry)
+
+	Do("key", "body", func() Response { return Response{Code: http.StatusOK} })
+	if _, _, err := Do("key", "other body", func() Response { return Response{Code: http.StatusOK} }); err != ErrKeyReused {
+		t.Errorf("expected ErrKeyReused, got %v", err)
+	}
+}
+
+func TestPanic(t *testing.T) {
+	entries = make(map[string]*entry)
+
+	func() {
+		defer func() { recover() }()
+		Do("key", "body", func() Response { panic("boom") })
+	}()
+
+	response, replayed, err := Do("key", "body", func() Response { return Response{Code: http.StatusOK} })
+	if err != nil || replayed || response.Code != http.StatusOK {
+		t.Errorf("expected the request to run again after a panic, got %v (replayed %v, err %v)", response.Code, replayed, err)
+	}
+}
+
+func TestWaiters(t *testing.T) {
+	entries = make(map[string]*entry)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go Do("key", "body", func() Response {
+		close(started)
+		<-release
+		return Response{Code: http.StatusOK}
+	})
+	<-started
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			response, replayed, _ := Do("key", "body", func() Response { return Response{Code: http.StatusTeapot} })
+			if !replayed || response.Code != http.StatusOK {
+				t.Errorf("expected to wait for the first response, got %v (replayed %v)", response.Code, replayed)
+			}
+		}()
+	}
+
+	close(release)
+	wg.Wait()
+}
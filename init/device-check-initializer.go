@@ -0,0 +1,86 @@
+package init
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	ce "github.com/byuoitav/av-api/commandevaluators"
+	"github.com/byuoitav/av-api/health"
+	"github.com/byuoitav/av-api/state"
+	"github.com/byuoitav/common/db"
+	"github.com/byuoitav/common/log"
+	"github.com/byuoitav/common/structs"
+	"github.com/fatih/color"
+)
+
+//PowerStatusCommand is the status query every controllable device is expected to answer.
+const PowerStatusCommand = "STATUS_Power"
+
+//DeviceCheckInitializer makes sure every device in the room answers a status query.
+type DeviceCheckInitializer struct {
+}
+
+//Initialize queries each device with a power status command, retrying the ones that don't answer.
+//Devices that never answer degrade the room's health, but don't fail initialization.
+func (i *DeviceCheckInitializer) Initialize(room structs.Room) error {
+	log.L.Infof("%s", color.HiBlueString("[init] checking the devices in %s", room.ID))
+
+	var devices []structs.Device
+	err := retry("loading devices", func() error {
+		var err error
+		devices, err = db.GetDB().GetDevicesByRoom(room.ID)
+		return err
+	})
+	if err != nil {
+		health.SetStatus("Startup Device Check", "ERROR: "+err.Error())
+		return fmt.Errorf("unable to load the devices in %s: %s", room.ID, err.Error())
+	}
+
+	var checked []structs.Device
+	for _, device := range devices {
+		if has, _ := ce.CheckCommands(device.Type.Commands, PowerStatusCommand); has {
+			checked = append(checked, device)
+		}
+	}
+
+	//devices are checked at once, so a few that are off don't hold up the rest of the room for all of their retries
+	answered := make([]bool, len(checked))
+	var wg sync.WaitGroup
+	for i := range checked {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			device := checked[i]
+			err := retry("checking "+device.ID, func() error {
+				_, err := state.ProbeDevice(device, PowerStatusCommand)
+				return err
+			})
+			if err != nil {
+				log.L.Warnf("%s", color.HiYellowString("[init] %s isn't answering: %s", device.ID, err.Error()))
+				return
+			}
+
+			answered[i] = true
+		}(i)
+	}
+	wg.Wait()
+
+	var unreachable []string
+	for i, device := range checked {
+		if !answered[i] {
+			unreachable = append(unreachable, device.Name)
+		}
+	}
+
+	//a device that's turned off at the wall isn't a reason for the room not to start, just for someone to go look at it
+	if len(unreachable) > 0 {
+		health.SetStatus("Startup Device Check", fmt.Sprintf("DEGRADED: %v of %v devices not answering: %s", len(unreachable), len(checked), strings.Join(unreachable, ", ")))
+		return nil
+	}
+
+	log.L.Infof("%s", color.HiGreenString("[init] all %v devices in %s answered", len(checked), room.ID))
+	health.SetStatus("Startup Device Check", "ok")
+	return nil
+}
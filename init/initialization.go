@@ -7,6 +7,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/byuoitav/av-api/health"
+	"github.com/byuoitav/av-api/registry"
+	"github.com/byuoitav/av-api/state"
 	"github.com/byuoitav/common/db"
 	"github.com/byuoitav/common/log"
 	"github.com/byuoitav/common/structs"
@@ -66,6 +69,18 @@ func CheckRoomInitialization() error {
 		}
 	}
 
+	//make sure everything the room's configuration refers to exists before we run any of it
+	uses := state.ConfigurationUses(room)
+	if len(room.Configuration.Description) > 0 {
+		uses[registry.Initializer] = []string{room.Configuration.Description}
+	}
+
+	err = registry.Check(uses)
+	if err != nil {
+		health.SetStatus("Room Initialization", "ERROR: "+err.Error())
+		return err
+	}
+
 	//There is no initializer, no need to run code
 	if len(room.Configuration.Description) < 1 {
 		return nil
@@ -74,7 +89,13 @@ func CheckRoomInitialization() error {
 	//take our room and get the init key
 	initMap := getMap()
 	if initializor, ok := initMap[room.Configuration.Description]; ok {
-		initializor.Initialize(room)
+		err = initializor.Initialize(room)
+		if err != nil {
+			health.SetStatus("Room Initialization", "ERROR: "+err.Error())
+			return err
+		}
+
+		health.SetStatus("Room Initialization", "ok")
 		return nil
 	}
 
@@ -93,16 +114,32 @@ type RoomInitializer interface {
 	Initialize(structs.Room) error
 }
 
-//InitializerMap is the map that contains the initializers
+//InitializerMap is the map that contains the initializers, filled in by Register
 var InitializerMap = make(map[string]RoomInitializer)
-var roomInitializerBuilt = false
 
-//Init builds or returns the CommandMap
-func getMap() map[string]RoomInitializer {
-	if !roomInitializerBuilt {
-		//Add the new initializers here
-		InitializerMap["Default"] = &DefaultInitializer{}
+//Register adds an initializer under key. The registry reports a key that is registered twice.
+func Register(key string, initializer RoomInitializer) {
+	registry.Register(registry.Entry{
+		Key:   key,
+		Kind:  registry.Initializer,
+		Value: initializer,
+	})
+
+	if _, ok := InitializerMap[key]; !ok {
+		InitializerMap[key] = initializer
 	}
+}
 
+func init() {
+	//Add the new initializers here
+	Register("Default", &DefaultInitializer{})
+	Register("Startup", &StartupInitializer{})
+	Register("Prewarm", &PrewarmInitializer{})
+	Register("DeviceCheck", &DeviceCheckInitializer{})
+	Register("StartupScene", &SceneInitializer{})
+}
+
+//getMap returns the InitializerMap
+func getMap() map[string]RoomInitializer {
 	return InitializerMap
 }
@@ -0,0 +1,60 @@
+package init
+
+import (
+	"fmt"
+
+	"github.com/byuoitav/av-api/gateway"
+	"github.com/byuoitav/av-api/health"
+	"github.com/byuoitav/common/db"
+	"github.com/byuoitav/common/log"
+	"github.com/byuoitav/common/structs"
+	"github.com/fatih/color"
+)
+
+//PrewarmInitializer looks up everything the first request to the room will need, so it isn't
+//the one waiting on the configuration database and the gateways after a reboot.
+type PrewarmInitializer struct {
+}
+
+//Initialize loads the room's devices and fills the gateway cache for each gated device.
+//Gateways that can't be resolved degrade the room's health, but don't fail initialization.
+func (i *PrewarmInitializer) Initialize(room structs.Room) error {
+	log.L.Infof("%s", color.HiBlueString("[init] pre-warming configuration for %s", room.ID))
+
+	var devices []structs.Device
+	err := retry("loading devices", func() error {
+		var err error
+		devices, err = db.GetDB().GetDevicesByRoom(room.ID)
+		return err
+	})
+	if err != nil {
+		health.SetStatus("Startup Pre-warm", "ERROR: "+err.Error())
+		return fmt.Errorf("unable to load the devices in %s: %s", room.ID, err.Error())
+	}
+
+	failed, gated := 0, 0
+	for _, device := range devices {
+		if !structs.HasRole(device, "GatedDevice") {
+			continue
+		}
+		gated++
+
+		err = retry("resolving the gateway for "+device.ID, func() error {
+			return gateway.Prewarm(device)
+		})
+		if err != nil {
+			log.L.Warnf("[init] unable to resolve the gateway for %s: %s", device.ID, err.Error())
+			failed++
+		}
+	}
+
+	//a gateway that isn't cached is just looked up by the first request that needs it
+	if failed > 0 {
+		health.SetStatus("Startup Pre-warm", fmt.Sprintf("DEGRADED: unable to resolve gateways for %v of %v gated devices", failed, gated))
+		return nil
+	}
+
+	log.L.Infof("[init] resolved the gateways of %v devices in %s", gated, room.ID)
+	health.SetStatus("Startup Pre-warm", "ok")
+	return nil
+}
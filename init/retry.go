@@ -0,0 +1,54 @@
+package init
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/byuoitav/common/log"
+)
+
+//DefaultRetries is how many times a step is tried if INIT_RETRIES isn't set.
+const DefaultRetries = 5
+
+//DefaultRetryDelay is how long to wait between tries if INIT_RETRY_DELAY isn't set.
+const DefaultRetryDelay = 5 * time.Second
+
+//GetRetries returns how many times each startup step is tried, from INIT_RETRIES, or DefaultRetries.
+func GetRetries() int {
+	retries, err := strconv.Atoi(os.Getenv("INIT_RETRIES"))
+	if err != nil || retries < 1 {
+		return DefaultRetries
+	}
+
+	return retries
+}
+
+//GetRetryDelay returns how long to wait between tries, from INIT_RETRY_DELAY, or DefaultRetryDelay.
+func GetRetryDelay() time.Duration {
+	delay, err := time.ParseDuration(os.Getenv("INIT_RETRY_DELAY"))
+	if err != nil || delay <= 0 {
+		return DefaultRetryDelay
+	}
+
+	return delay
+}
+
+//retry runs fn until it succeeds or we run out of tries. Devices are often still booting
+//when the Pi comes back from a power blip, so a failure or two is expected.
+func retry(step string, fn func() error) error {
+	var err error
+	for attempt := 1; attempt <= GetRetries(); attempt++ {
+		err = fn()
+		if err == nil {
+			return nil
+		}
+
+		log.L.Warnf("[init] %s failed (attempt %v of %v): %s", step, attempt, GetRetries(), err.Error())
+		if attempt < GetRetries() {
+			time.Sleep(GetRetryDelay())
+		}
+	}
+
+	return err
+}
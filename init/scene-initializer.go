@@ -0,0 +1,80 @@
+package init
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/av-api/health"
+	"github.com/byuoitav/av-api/state"
+	"github.com/byuoitav/common/log"
+	"github.com/byuoitav/common/structs"
+	"github.com/fatih/color"
+)
+
+//SceneRequestor is the requestor the startup scene is applied as.
+const SceneRequestor = "startup-initializer"
+
+//SceneInitializer puts the room into a known state when the API starts, e.g. after a power blip.
+type SceneInitializer struct {
+}
+
+//GetStartupScene reads the room state in the file at STARTUP_SCENE_PATH. It reports false if there isn't one.
+func GetStartupScene() (base.PublicRoom, bool, error) {
+	path := os.Getenv("STARTUP_SCENE_PATH")
+	if len(path) == 0 {
+		return base.PublicRoom{}, false, nil
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return base.PublicRoom{}, false, fmt.Errorf("unable to read startup scene: %s", err.Error())
+	}
+
+	var scene base.PublicRoom
+	err = json.Unmarshal(b, &scene)
+	if err != nil {
+		return base.PublicRoom{}, false, fmt.Errorf("unable to parse startup scene: %s", err.Error())
+	}
+
+	return scene, true, nil
+}
+
+//Initialize applies the startup scene through SetRoomState, the same as a PUT to the room would.
+func (i *SceneInitializer) Initialize(room structs.Room) error {
+	scene, ok, err := GetStartupScene()
+	if err != nil {
+		health.SetStatus("Startup Scene", "ERROR: "+err.Error())
+		return err
+	}
+
+	if !ok {
+		log.L.Info("[init] No startup scene configured.")
+		health.SetStatus("Startup Scene", "none configured")
+		return nil
+	}
+
+	split := strings.SplitN(room.ID, "-", 2)
+	if len(split) != 2 {
+		return fmt.Errorf("invalid room ID %s", room.ID)
+	}
+	scene.Building = split[0]
+	scene.Room = split[1]
+
+	log.L.Infof("%s", color.HiBlueString("[init] applying the startup scene to %s", room.ID))
+
+	err = retry("applying the startup scene", func() error {
+		_, err := state.SetRoomState(scene, SceneRequestor)
+		return err
+	})
+	if err != nil {
+		health.SetStatus("Startup Scene", "ERROR: "+err.Error())
+		return fmt.Errorf("unable to apply the startup scene: %s", err.Error())
+	}
+
+	health.SetStatus("Startup Scene", "ok")
+	return nil
+}
@@ -0,0 +1,39 @@
+package init
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/byuoitav/common/log"
+	"github.com/byuoitav/common/structs"
+)
+
+//StartupInitializer pre-warms the configuration, checks the devices, then applies the startup scene,
+//if there is one.
+type StartupInitializer struct {
+}
+
+//Initialize runs each step in turn, and reports every step that failed.
+func (i *StartupInitializer) Initialize(room structs.Room) error {
+	steps := []RoomInitializer{
+		&PrewarmInitializer{},
+		&DeviceCheckInitializer{},
+		&SceneInitializer{},
+	}
+
+	//a device that isn't answering shouldn't keep the rest of the room from being set up
+	var failures []string
+	for _, step := range steps {
+		err := step.Initialize(room)
+		if err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("%s", strings.Join(failures, "; "))
+	}
+
+	log.L.Info("[init] Room initialized.")
+	return nil
+}
@@ -0,0 +1,190 @@
+package locks
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/common/log"
+)
+
+// DefaultTTL is how long a lease lasts if the requestor doesn't ask for something else, and LOCK_DEFAULT_TTL isn't set.
+const DefaultTTL = 5 * time.Minute
+
+// MaxTTL is the longest lease a requestor can take out at once.
+const MaxTTL = 4 * time.Hour
+
+// ErrLocked is returned when a request conflicts with a lease held by someone else.
+type ErrLocked struct {
+	Lock base.Lock
+}
+
+func (e *ErrLocked) Error() string {
+	target := "the room"
+	if len(e.Lock.Devices) > 0 {
+		target = strings.Join(e.Lock.Devices, ", ")
+	}
+
+	return fmt.Sprintf("%s is locked by %s until %s", target, e.Lock.Holder, e.Lock.Expires.Format(time.RFC3339))
+}
+
+var leases = make(map[string][]base.Lock)
+var mutex sync.Mutex
+
+// GetDefaultTTL returns the lease length set in LOCK_DEFAULT_TTL, or DefaultTTL.
+func GetDefaultTTL() time.Duration {
+	ttl, err := time.ParseDuration(os.Getenv("LOCK_DEFAULT_TTL"))
+	if err != nil || ttl <= 0 {
+		return DefaultTTL
+	}
+
+	return ttl
+}
+
+// Acquire takes out (or renews) a lease on a room for holder. An empty devices list locks the whole room.
+// If the lease conflicts with one held by someone else, Acquire fails unless override is set,
+// in which case the conflicting leases are broken.
+func Acquire(building, room, holder string, devices []string, ttl time.Duration, override bool) (base.Lock, error) {
+	if ttl <= 0 {
+		ttl = GetDefaultTTL()
+	}
+	if ttl > MaxTTL {
+		ttl = MaxTTL
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	roomID := fmt.Sprintf("%v-%v", building, room)
+	current := active(roomID)
+
+	var kept []base.Lock
+	for _, lease := range current {
+		switch {
+		case strings.EqualFold(lease.Holder, holder):
+			//the new lease replaces the old one
+			continue
+		case !overlaps(lease.Devices, devices):
+			kept = append(kept, lease)
+		case override:
+			log.L.Infof("[locks] %s is breaking the lock %s holds on %s", holder, lease.Holder, roomID)
+		default:
+			return base.Lock{}, &ErrLocked{Lock: lease}
+		}
+	}
+
+	now := time.Now()
+	lease := base.Lock{
+		Holder:   holder,
+		Devices:  devices,
+		Acquired: now,
+		Expires:  now.Add(ttl),
+	}
+
+	leases[roomID] = append(kept, lease)
+	log.L.Infof("[locks] %s locked %s until %s", holder, roomID, lease.Expires.Format(time.RFC3339))
+
+	return lease, nil
+}
+
+// Release gives up the lease holder has on a room. With override, every lease on the room is released.
+func Release(building, room, holder string, override bool) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	roomID := fmt.Sprintf("%v-%v", building, room)
+	current := active(roomID)
+
+	var kept []base.Lock
+	for _, lease := range current {
+		if !override && !strings.EqualFold(lease.Holder, holder) {
+			kept = append(kept, lease)
+		}
+	}
+
+	if len(kept) == len(current) {
+		return fmt.Errorf("%s doesn't hold a lock on %s", holder, roomID)
+	}
+
+	leases[roomID] = kept
+	log.L.Infof("[locks] %s released their lock on %s", holder, roomID)
+
+	return nil
+}
+
+// Check makes sure requestor can change the given devices in a room. An empty devices list means the whole room.
+// A lease held by the requestor is renewed for another full term.
+func Check(building, room, requestor string, devices []string) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	roomID := fmt.Sprintf("%v-%v", building, room)
+	current := active(roomID)
+
+	for i, lease := range current {
+		if strings.EqualFold(lease.Holder, requestor) {
+			current[i].Expires = time.Now().Add(lease.Expires.Sub(lease.Acquired))
+			current[i].Acquired = time.Now()
+			continue
+		}
+
+		if overlaps(lease.Devices, devices) {
+			return &ErrLocked{Lock: lease}
+		}
+	}
+
+	return nil
+}
+
+// Get returns the leases currently held on a room.
+func Get(building, room string) []base.Lock {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	current := active(fmt.Sprintf("%v-%v", building, room))
+
+	output := make([]base.Lock, len(current))
+	copy(output, current)
+
+	return output
+}
+
+//active drops any expired leases on the room, and returns the rest. Must be called with the mutex held.
+func active(roomID string) []base.Lock {
+	var current []base.Lock
+	for _, lease := range leases[roomID] {
+		if time.Now().Before(lease.Expires) {
+			current = append(current, lease)
+		} else {
+			log.L.Infof("[locks] lock %s held on %s has expired", lease.Holder, roomID)
+		}
+	}
+
+	if len(current) == 0 {
+		delete(leases, roomID)
+	} else {
+		leases[roomID] = current
+	}
+
+	return current
+}
+
+//overlaps reports whether two sets of devices share anything. An empty set means every device in the room.
+func overlaps(a, b []string) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return true
+	}
+
+	for _, x := range a {
+		for _, y := range b {
+			if strings.EqualFold(x, y) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,112 @@
+package locks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/byuoitav/av-api/base"
+)
+
+func TestLeases(t *testing.T) {
+	tests := []struct {
+		name      string
+		existing  []base.Lock
+		holder    string
+		devices   []string
+		override  bool
+		acquire   bool
+		remaining int
+	}{
+		{"free room", nil, "cp1", nil, false, true, 1},
+		{"room locked by someone else", []base.Lock{lease("cp2", nil, time.Minute)}, "cp1", nil, false, false, 1},
+		{"other devices locked", []base.Lock{lease("cp2", []string{"D1"}, time.Minute)}, "cp1", []string{"D2"}, false, true, 2},
+		{"same device locked", []base.Lock{lease("cp2", []string{"D1"}, time.Minute)}, "cp1", []string{"d1"}, false, false, 1},
+		{"renewing own lease", []base.Lock{lease("cp1", nil, time.Minute)}, "CP1", []string{"D1"}, false, true, 1},
+		{"expired lease", []base.Lock{lease("cp2", nil, -time.Minute)}, "cp1", nil, false, true, 1},
+		{"override", []base.Lock{lease("cp2", nil, time.Minute), lease("cp3", []string{"D1"}, time.Minute)}, "tech", []string{"D2"}, true, true, 2},
+	}
+
+	for _, test := range tests {
+		leases = map[string][]base.Lock{"ITB-1101": test.existing}
+
+		_, err := Acquire("ITB", "1101", test.holder, test.devices, time.Minute, test.override)
+		if test.acquire && err != nil {
+			t.Errorf("%s: expected the lease to be granted: %s", test.name, err)
+		}
+		if !test.acquire {
+			if _, ok := err.(*ErrLocked); !ok {
+				t.Errorf("%s: expected ErrLocked, got %v", test.name, err)
+			}
+		}
+
+		if current := Get("ITB", "1101"); len(current) != test.remaining {
+			t.Errorf("%s: expected %v leases, got %v", test.name, test.remaining, len(current))
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name      string
+		existing  []base.Lock
+		requestor string
+		devices   []string
+		allowed   bool
+	}{
+		{"no leases", nil, "cp1", nil, true},
+		{"holder", []base.Lock{lease("cp1", nil, time.Minute)}, "cp1", nil, true},
+		{"room locked", []base.Lock{lease("cp1", nil, time.Minute)}, "cp2", []string{"D1"}, false},
+		{"other device locked", []base.Lock{lease("cp1", []string{"D1"}, time.Minute)}, "cp2", []string{"D2"}, true},
+		{"whole room request against device lock", []base.Lock{lease("cp1", []string{"D1"}, time.Minute)}, "cp2", nil, false},
+		{"expired", []base.Lock{lease("cp1", nil, -time.Second)}, "cp2", nil, true},
+	}
+
+	for _, test := range tests {
+		leases = map[string][]base.Lock{"ITB-1101": test.existing}
+
+		err := Check("ITB", "1101", test.requestor, test.devices)
+		if test.allowed && err != nil {
+			t.Errorf("%s: expected the request to be allowed: %s", test.name, err)
+		}
+		if !test.allowed && err == nil {
+			t.Errorf("%s: expected the request to be locked out", test.name)
+		}
+	}
+}
+
+func TestCheckRenews(t *testing.T) {
+	old := lease("cp1", nil, time.Minute)
+	old.Acquired = old.Acquired.Add(-30 * time.Second)
+	old.Expires = old.Expires.Add(-30 * time.Second)
+	leases = map[string][]base.Lock{"ITB-1101": {old}}
+
+	if err := Check("ITB", "1101", "cp1", nil); err != nil {
+		t.Fatalf("expected the holder to be allowed: %s", err)
+	}
+
+	renewed := Get("ITB", "1101")[0]
+	if !renewed.Expires.After(old.Expires) {
+		t.Errorf("expected the lease to be renewed past %s, got %s", old.Expires, renewed.Expires)
+	}
+}
+
+func TestRelease(t *testing.T) {
+	leases = map[string][]base.Lock{"ITB-1101": {lease("cp1", []string{"D1"}, time.Minute), lease("cp2", []string{"D2"}, time.Minute)}}
+
+	if err := Release("ITB", "1101", "cp3", false); err == nil {
+		t.Error("expected releasing someone else's lease to fail")
+	}
+
+	if err := Release("ITB", "1101", "cp1", false); err != nil || len(Get("ITB", "1101")) != 1 {
+		t.Errorf("expected only cp1's lease to be released: %v", err)
+	}
+
+	if err := Release("ITB", "1101", "tech", true); err != nil || len(Get("ITB", "1101")) != 0 {
+		t.Errorf("expected an override to release every lease: %v", err)
+	}
+}
+
+func lease(holder string, devices []string, ttl time.Duration) base.Lock {
+	now := time.Now()
+	return base.Lock{Holder: holder, Devices: devices, Acquired: now, Expires: now.Add(ttl)}
+}
@@ -0,0 +1,146 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Kind is what sort of thing a registered entry is.
+type Kind string
+
+// The kinds of things that can be registered.
+const (
+	Command     Kind = "command"
+	Status      Kind = "status"
+	Reconciler  Kind = "reconciler"
+	Initializer Kind = "initializer"
+)
+
+// Entry is a registered evaluator, reconciler or initializer.
+// Command evaluators name the status evaluator that reads their responses, the commands they send,
+// and the commands they can't be run with.
+type Entry struct {
+	Key          string      `json:"key"`
+	Kind         Kind        `json:"kind"`
+	Status       string      `json:"status,omitempty"`
+	Commands     []string    `json:"commands,omitempty"`
+	Incompatible []string    `json:"incompatible,omitempty"`
+	Value        interface{} `json:"-"`
+}
+
+// Uses lists the keys a room's configuration refers to, by kind.
+type Uses map[Kind][]string
+
+var entries = make(map[Kind]map[string]Entry)
+var problems []string
+var mutex sync.RWMutex
+
+// Register adds an entry. Registering the same key twice for a kind, even spelled with different case,
+// is a problem that Check reports; the first registration is kept.
+func Register(entry Entry) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if len(entry.Key) == 0 {
+		problems = append(problems, fmt.Sprintf("a %s was registered without a key", entry.Kind))
+		return
+	}
+
+	if _, ok := entries[entry.Kind]; !ok {
+		entries[entry.Kind] = make(map[string]Entry)
+	}
+
+	for key := range entries[entry.Kind] {
+		if strings.EqualFold(key, entry.Key) {
+			problems = append(problems, fmt.Sprintf("%s %s was registered more than once", entry.Kind, entry.Key))
+			return
+		}
+	}
+
+	entries[entry.Kind][entry.Key] = entry
+}
+
+// Get returns the entry registered under key for a kind.
+func Get(kind Kind, key string) (Entry, bool) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	entry, ok := entries[kind][key]
+	return entry, ok
+}
+
+// List returns every entry, sorted by kind and then key.
+func List() []Entry {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	var output []Entry
+	for _, byKey := range entries {
+		for _, entry := range byKey {
+			output = append(output, entry)
+		}
+	}
+
+	sort.Slice(output, func(i, j int) bool {
+		if output[i].Kind != output[j].Kind {
+			return output[i].Kind < output[j].Kind
+		}
+		return output[i].Key < output[j].Key
+	})
+
+	return output
+}
+
+// Check makes sure everything registered is consistent: no key is registered twice, every command evaluator
+// is paired with a status evaluator that exists, and only lists commands some command evaluator sends as incompatible.
+// It also makes sure everything the room configurations in uses refer to is registered.
+func Check(uses ...Uses) error {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	found := append([]string{}, problems...)
+
+	sent := make(map[string]bool)
+	for _, entry := range entries[Command] {
+		for _, command := range entry.Commands {
+			sent[strings.ToLower(command)] = true
+		}
+	}
+
+	for key, entry := range entries[Command] {
+		if len(entry.Status) == 0 {
+			found = append(found, fmt.Sprintf("command %s isn't paired with a status evaluator", key))
+			continue
+		}
+
+		if _, ok := entries[Status][entry.Status]; !ok {
+			found = append(found, fmt.Sprintf("command %s is paired with status %s, which isn't registered", key, entry.Status))
+		}
+
+		for _, command := range entry.Incompatible {
+			if !sent[strings.ToLower(command)] {
+				found = append(found, fmt.Sprintf("command %s lists %s as incompatible, but no command evaluator sends it", key, command))
+			}
+		}
+	}
+
+	for _, use := range uses {
+		for kind, keys := range use {
+			for _, key := range keys {
+				if _, ok := entries[kind][key]; !ok {
+					found = append(found, fmt.Sprintf("the room configuration uses %s %s, which isn't registered", kind, key))
+				}
+			}
+		}
+	}
+
+	if len(found) > 0 {
+		sort.Strings(found)
+		return errors.New(strings.Join(found, "; "))
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package registry
+
+import (
+	"strings"
+	"testing"
+)
+
+// reset clears everything registered, so each test starts from nothing.
+func reset() {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	entries = make(map[Kind]map[string]Entry)
+	problems = nil
+}
+
+func registerDefaults() {
+	Register(Entry{Key: "STATUS_PowerDefault", Kind: Status})
+	Register(Entry{Key: "PowerOnDefault", Kind: Command, Status: "STATUS_PowerDefault", Commands: []string{"PowerOn"}, Incompatible: []string{"standby"}})
+	Register(Entry{Key: "StandbyDefault", Kind: Command, Status: "STATUS_PowerDefault", Commands: []string{"Standby"}, Incompatible: []string{"PowerOn"}})
+	Register(Entry{Key: "Default", Kind: Reconciler})
+	Register(Entry{Key: "Default", Kind: Initializer})
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		register []Entry
+		uses     []Uses
+		problems []string
+	}{
+		{
+			name: "consistent",
+			uses: []Uses{{Command: {"PowerOnDefault"}, Status: {"STATUS_PowerDefault"}, Reconciler: {"Default"}, Initializer: {"Default"}}},
+		},
+		{
+			name:     "duplicate",
+			register: []Entry{{Key: "PowerOnDefault", Kind: Command, Status: "STATUS_PowerDefault"}},
+			problems: []string{"command PowerOnDefault was registered more than once"},
+		},
+		{
+			name:     "duplicate with different case",
+			register: []Entry{{Key: "PoweronDefault", Kind: Command, Status: "STATUS_PowerDefault"}},
+			problems: []string{"command PoweronDefault was registered more than once"},
+		},
+		{
+			name:     "same key, different kinds",
+			register: []Entry{{Key: "PowerOnDefault", Kind: Reconciler}},
+		},
+		{
+			name:     "missing status",
+			register: []Entry{{Key: "MuteDefault", Kind: Command, Status: "STATUS_MutedDefault", Commands: []string{"Mute"}}},
+			problems: []string{"command MuteDefault is paired with status STATUS_MutedDefault, which isn't registered"},
+		},
+		{
+			name:     "unknown incompatible command",
+			register: []Entry{{Key: "UnBlankDisplayDefault", Kind: Command, Status: "STATUS_PowerDefault", Commands: []string{"UnblankDisplay"}, Incompatible: []string{"BlankScreen"}}},
+			problems: []string{"command UnBlankDisplayDefault lists BlankScreen as incompatible, but no command evaluator sends it"},
+		},
+		{
+			name: "unregistered uses",
+			uses: []Uses{{Command: {"UnmuteDSP"}, Reconciler: {"RoleBased"}, Initializer: {"Prewarm"}}},
+			problems: []string{
+				"the room configuration uses command UnmuteDSP, which isn't registered",
+				"the room configuration uses initializer Prewarm, which isn't registered",
+				"the room configuration uses reconciler RoleBased, which isn't registered",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		reset()
+		registerDefaults()
+		for _, entry := range test.register {
+			Register(entry)
+		}
+
+		err := Check(test.uses...)
+		if len(test.problems) == 0 {
+			if err != nil {
+				t.Errorf("%s: expected no problems, got %s", test.name, err)
+			}
+			continue
+		}
+
+		if err == nil {
+			t.Errorf("%s: expected %v, got no problems", test.name, test.problems)
+			continue
+		}
+
+		found := strings.Split(err.Error(), "; ")
+		if strings.Join(found, "\n") != strings.Join(test.problems, "\n") {
+			t.Errorf("%s: expected %v, got %v", test.name, test.problems, found)
+		}
+	}
+}
+
+func TestRegisterKeepsFirst(t *testing.T) {
+	reset()
+	Register(Entry{Key: "Default", Kind: Reconciler, Value: 1})
+	Register(Entry{Key: "default", Kind: Reconciler, Value: 2})
+
+	entry, ok := Get(Reconciler, "Default")
+	if !ok || entry.Value != 1 {
+		t.Errorf("expected the first registration to be kept, got %+v", entry)
+	}
+
+	if _, ok := Get(Reconciler, "default"); ok {
+		t.Error("expected the second registration to be dropped")
+	}
+
+	if len(List()) != 1 {
+		t.Errorf("expected one entry, got %v", List())
+	}
+}
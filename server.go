@@ -6,10 +6,17 @@ import (
 	"os"
 
 	"github.com/byuoitav/authmiddleware"
+	"github.com/byuoitav/av-api/actionreconcilers"
+	"github.com/byuoitav/av-api/authorization"
 	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/av-api/combine"
 	"github.com/byuoitav/av-api/handlers"
 	"github.com/byuoitav/av-api/health"
 	avapi "github.com/byuoitav/av-api/init"
+	"github.com/byuoitav/av-api/registry"
+	"github.com/byuoitav/av-api/sinks"
+	"github.com/byuoitav/av-api/state"
+	se "github.com/byuoitav/av-api/statusevaluators"
 	"github.com/byuoitav/common/db"
 	ei "github.com/byuoitav/common/events"
 	"github.com/byuoitav/common/log"
@@ -22,6 +29,48 @@ import (
 func main() {
 	base.EventNode = ei.NewEventNode("AV-API", os.Getenv("EVENT_ROUTER_ADDRESS"), []string{})
 
+	eventSinks, err := sinks.Load(base.EventNode)
+	if err != nil {
+		log.L.Fatalf("Could not load the event sinks: %s", err.Error())
+	}
+	base.Sinks = eventSinks
+
+	err = authorization.Init()
+	if err != nil {
+		log.L.Fatalf("Could not load the authorization policies: %s", err.Error())
+	}
+
+	err = combine.Load()
+	if err != nil {
+		log.L.Fatalf("Could not restore the combined rooms: %s", err.Error())
+	}
+
+	err = actionreconcilers.LoadDependencyRules()
+	if err != nil {
+		log.L.Fatalf("Could not load the dependency rules: %s", err.Error())
+	}
+
+	err = state.LoadSequencing()
+	if err != nil {
+		log.L.Fatalf("Could not load the sequencing config: %s", err.Error())
+	}
+
+	err = state.LoadDriftPolicies()
+	if err != nil {
+		log.L.Fatalf("Could not load the drift policies: %s", err.Error())
+	}
+
+	_, err = se.GetInfoFields()
+	if err != nil {
+		log.L.Fatalf("Could not load the info fields: %s", err.Error())
+	}
+
+	//make sure the evaluators all line up before we take any requests
+	err = registry.Check()
+	if err != nil {
+		log.L.Fatalf("Evaluators are misconfigured: %s", err.Error())
+	}
+
 	go func() {
 		err := avapi.CheckRoomInitialization()
 		if err != nil {
@@ -44,10 +93,31 @@ func main() {
 
 	// PUT requests
 	secure.PUT("/buildings/:building/rooms/:room", handlers.SetRoomState)
+	secure.PUT("/buildings/:building", handlers.SetBuildingState)
+	secure.PUT("/buildings/:building/rooms/:room/devices/:device", handlers.SetDeviceState)
+
+	// raw device commands
+	secure.POST("/buildings/:building/rooms/:room/devices/:device/commands/:commandID", handlers.ExecuteRawCommand)
+
+	// room locks
+	secure.POST("/buildings/:building/rooms/:room/lock", handlers.LockRoom)
+	secure.DELETE("/buildings/:building/rooms/:room/lock", handlers.UnlockRoom)
+
+	// combined rooms
+	secure.GET("/buildings/:building/rooms/:room/combine", handlers.GetCombinedRooms)
+	secure.POST("/buildings/:building/rooms/:room/combine", handlers.CombineRooms)
+	secure.DELETE("/buildings/:building/rooms/:room/combine", handlers.SeparateRooms)
 
 	// room status
 	secure.GET("/buildings/:building/rooms/:room", handlers.GetRoomState)
 	secure.GET("/buildings/:building/rooms/:room/configuration", handlers.GetRoomByNameAndBuilding)
+	secure.GET("/buildings/:building/rooms/:room/devices/:device", handlers.GetDeviceState)
+
+	// audit log of room control requests
+	secure.GET("/audit", handlers.GetAuditRecords)
+
+	// registered evaluators, reconcilers and initializers
+	secure.GET("/evaluators", handlers.GetEvaluators)
 
 	server := http.Server{
 		Addr:           port,
@@ -56,6 +126,14 @@ func main() {
 
 	go health.StartupCheckAndReport()
 
+	if len(os.Getenv("LOCAL_ENVIRONMENT")) > 0 {
+		go health.StartDeviceChecks()
+	}
+
+	if state.EnforcementEnabled() {
+		go state.StartEnforcer()
+	}
+
 	router.StartServer(&server)
 }
 
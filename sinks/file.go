@@ -0,0 +1,89 @@
+package sinks
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/byuoitav/common/events"
+)
+
+// DefaultFilePath is where events are written if a file sink doesn't have a path.
+const DefaultFilePath = "events.jsonl"
+
+// DefaultMaxSize is how big the file gets before it's rotated, if the sink doesn't say.
+const DefaultMaxSize = 10 * 1024 * 1024
+
+// DefaultMaxFiles is how many rotated files are kept, if the sink doesn't say.
+const DefaultMaxFiles = 5
+
+// FileSink appends each event to a file as a line of JSON. Once the file passes its size limit,
+// it's moved to path.1, path.1 to path.2, and so on, and the oldest is deleted.
+type FileSink struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+	mutex    sync.Mutex
+}
+
+// NewFileSink returns a sink that writes to path. Zero values get the defaults.
+func NewFileSink(path string, maxSize int64, maxFiles int) *FileSink {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	if maxFiles <= 0 {
+		maxFiles = DefaultMaxFiles
+	}
+
+	return &FileSink{path: path, maxSize: maxSize, maxFiles: maxFiles}
+}
+
+// Name fulfills the Sink interface.
+func (f *FileSink) Name() string {
+	return fmt.Sprintf("%s %s", File, f.path)
+}
+
+// Send fulfills the Sink interface.
+func (f *FileSink) Send(header string, e events.Event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if info, err := os.Stat(f.path); err == nil && info.Size()+int64(len(b))+1 > f.maxSize {
+		err = f.rotate()
+		if err != nil {
+			return fmt.Errorf("unable to rotate %s: %s", f.path, err.Error())
+		}
+	}
+
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(b, '\n'))
+	return err
+}
+
+//rotate shifts each old file up one. Must be called with the mutex held.
+func (f *FileSink) rotate() error {
+	os.Remove(fmt.Sprintf("%s.%d", f.path, f.maxFiles))
+
+	for i := f.maxFiles - 1; i > 0; i-- {
+		old := fmt.Sprintf("%s.%d", f.path, i)
+		if _, err := os.Stat(old); err == nil {
+			err = os.Rename(old, fmt.Sprintf("%s.%d", f.path, i+1))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return os.Rename(f.path, f.path+".1")
+}
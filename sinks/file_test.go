@@ -0,0 +1,59 @@
+package sinks
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/byuoitav/common/events"
+)
+
+func TestFileRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sinks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "events.jsonl")
+
+	//each event is about 100 bytes, so every file holds two
+	f := NewFileSink(path, 250, 2)
+	for i := 0; i < 7; i++ {
+		err = f.Send(events.APISuccess, event(events.INFO, fmt.Sprintf("event-%v", i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := map[string]string{
+		path:        "event-6",
+		path + ".1": "event-4,event-5",
+		path + ".2": "event-2,event-3",
+	}
+
+	for file, keys := range expected {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Errorf("expected %s to exist: %s", file, err)
+			continue
+		}
+
+		for _, key := range strings.Split(keys, ",") {
+			if !strings.Contains(string(b), key) {
+				t.Errorf("expected %s to have %s, got %s", file, key, b)
+			}
+		}
+
+		if lines := strings.Count(string(b), "\n"); lines != len(strings.Split(keys, ",")) {
+			t.Errorf("expected %s to have %v events, got %v", file, len(strings.Split(keys, ",")), lines)
+		}
+	}
+
+	if _, err := os.Stat(path + ".3"); err == nil {
+		t.Error("expected only 2 old files to be kept")
+	}
+}
@@ -0,0 +1,285 @@
+package sinks
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/byuoitav/common/events"
+	"github.com/byuoitav/common/log"
+	"github.com/fatih/color"
+)
+
+// DefaultQueuePath is where undelivered events are kept if EVENT_QUEUE_PATH isn't set.
+const DefaultQueuePath = "event-queue.jsonl"
+
+// DefaultQueueMax is the most events kept if EVENT_QUEUE_MAX isn't set.
+const DefaultQueueMax = 10000
+
+// DefaultReplayInterval is how often delivery is retried if EVENT_QUEUE_RETRY isn't set.
+const DefaultReplayInterval = 10 * time.Second
+
+// queued is an event waiting to be delivered, as it was first published.
+type queued struct {
+	Header string       `json:"header"`
+	Event  events.Event `json:"event"`
+}
+
+// QueuedSink keeps the events its sink couldn't take in a file, and replays them in order,
+// with their original timestamps, once the sink is back. New events wait behind the queued ones,
+// so nothing is delivered out of order. Once the queue is full, the oldest events are dropped.
+//
+// An event counts as delivered once the sink takes it. For the router sink that only means the
+// event node accepted it; the node buffers events itself and doesn't report a lost connection, so
+// an outage is only noticed when the router's address can't be dialed on the next retry, and
+// anything published in between is up to the node.
+type QueuedSink struct {
+	sink    Sink
+	path    string
+	max     int
+	address string
+
+	pending []queued
+	dropped int
+	down    bool
+	mutex   sync.Mutex
+}
+
+// GetQueueMax returns the most events that are kept, from EVENT_QUEUE_MAX, or DefaultQueueMax.
+func GetQueueMax() int {
+	max, err := strconv.Atoi(os.Getenv("EVENT_QUEUE_MAX"))
+	if err != nil || max <= 0 {
+		return DefaultQueueMax
+	}
+
+	return max
+}
+
+// NewQueuedSink puts a queue in front of sink, loading any events left over from before a restart.
+// If address isn't empty, it's dialed on every retry, so an outage is noticed even if sink doesn't report one.
+func NewQueuedSink(sink Sink, path string, max int, address string) *QueuedSink {
+	q := &QueuedSink{
+		sink:    sink,
+		path:    path,
+		max:     max,
+		address: address,
+	}
+
+	q.load()
+	if len(q.pending) > 0 {
+		log.L.Infof("%s", color.HiBlueString("[sinks] %v events waiting for %s from before a restart", len(q.pending), sink.Name()))
+	}
+
+	interval, err := time.ParseDuration(os.Getenv("EVENT_QUEUE_RETRY"))
+	if err != nil || interval <= 0 {
+		interval = DefaultReplayInterval
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		for range ticker.C {
+			q.replay()
+		}
+	}()
+
+	return q
+}
+
+// Name fulfills the Sink interface.
+func (q *QueuedSink) Name() string {
+	return q.sink.Name()
+}
+
+// Depth returns how many events are waiting to be delivered.
+func (q *QueuedSink) Depth() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return len(q.pending)
+}
+
+// Send fulfills the Sink interface. An event that can't be delivered is queued instead, so it isn't reported as an error.
+func (q *QueuedSink) Send(header string, e events.Event) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if !q.down && len(q.pending) == 0 {
+		err := q.sink.Send(header, e)
+		if err == nil {
+			return nil
+		}
+
+		log.L.Warnf("%s", color.HiYellowString("[sinks] %s is unreachable, queueing events: %s", q.sink.Name(), err.Error()))
+		q.down = true
+	}
+
+	q.enqueue(queued{Header: header, Event: e})
+	return nil
+}
+
+//enqueue adds an event to the end of the queue, dropping the oldest if it's full. Must be called with the mutex held.
+func (q *QueuedSink) enqueue(event queued) {
+	q.pending = append(q.pending, event)
+
+	if len(q.pending) > q.max {
+		dropped := len(q.pending) - q.max
+		q.pending = q.pending[dropped:]
+		q.dropped += dropped
+
+		log.L.Warnf("[sinks] event queue for %s is full, dropped the %v oldest events", q.sink.Name(), dropped)
+		q.save()
+		return
+	}
+
+	b, err := json.Marshal(event)
+	if err != nil {
+		log.L.Warnf("[sinks] unable to queue event: %s", err.Error())
+		return
+	}
+
+	file, err := os.OpenFile(q.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		log.L.Warnf("[sinks] unable to write event queue: %s", err.Error())
+		return
+	}
+	defer file.Close()
+
+	file.Write(append(b, '\n'))
+}
+
+//replay delivers the queued events in order, stopping at the first one that fails. The events are sent
+//without holding the mutex, so publishing isn't blocked while a long queue drains; anything published
+//in the meantime is queued behind them, and sent once they're done.
+func (q *QueuedSink) replay() {
+	if !q.reachable() {
+		q.mutex.Lock()
+		q.down = true
+		q.mutex.Unlock()
+		return
+	}
+
+	for {
+		q.mutex.Lock()
+		if len(q.pending) == 0 {
+			q.down = false
+			q.mutex.Unlock()
+			return
+		}
+
+		//keep new events queued until the replay is done
+		q.down = true
+		pending := make([]queued, len(q.pending))
+		copy(pending, q.pending)
+		dropped := q.dropped
+		q.mutex.Unlock()
+
+		log.L.Infof("%s", color.HiBlueString("[sinks] replaying %v queued events to %s", len(pending), q.sink.Name()))
+
+		sent := 0
+		for _, event := range pending {
+			err := q.sink.Send(event.Header, event.Event)
+			if err != nil {
+				log.L.Warnf("[sinks] %s is still unreachable: %s", q.sink.Name(), err.Error())
+				break
+			}
+			sent++
+		}
+
+		q.mutex.Lock()
+		done := sent == len(pending)
+
+		//if the queue filled up while replaying, some of what was sent has already been dropped
+		sent -= q.dropped - dropped
+		if sent > 0 {
+			q.pending = q.pending[sent:]
+		}
+
+		q.down = len(q.pending) > 0
+		q.save()
+		q.mutex.Unlock()
+
+		if !done {
+			return
+		}
+
+		if !q.down {
+			log.L.Infof("%s", color.HiGreenString("[sinks] delivered all queued events to %s", q.sink.Name()))
+		}
+	}
+}
+
+//reachable dials the sink's address, if it has one.
+func (q *QueuedSink) reachable() bool {
+	if len(q.address) == 0 {
+		return true
+	}
+
+	conn, err := net.DialTimeout("tcp", q.address, 2*time.Second)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+
+	return true
+}
+
+//load reads in the events left in the queue file.
+func (q *QueuedSink) load() {
+	file, err := os.Open(q.path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var event queued
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			log.L.Warnf("[sinks] skipping unreadable queued event: %s", err.Error())
+			continue
+		}
+
+		q.pending = append(q.pending, event)
+	}
+
+	if len(q.pending) > q.max {
+		q.pending = q.pending[len(q.pending)-q.max:]
+	}
+}
+
+//save rewrites the queue file with what's still pending. Must be called with the mutex held.
+func (q *QueuedSink) save() {
+	if len(q.pending) == 0 {
+		os.Remove(q.path)
+		return
+	}
+
+	tmp := q.path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		log.L.Warnf("[sinks] unable to write event queue: %s", err.Error())
+		return
+	}
+
+	writer := bufio.NewWriter(file)
+	for _, event := range q.pending {
+		b, err := json.Marshal(event)
+		if err != nil {
+			continue
+		}
+		writer.Write(append(b, '\n'))
+	}
+
+	writer.Flush()
+	file.Close()
+
+	err = os.Rename(tmp, q.path)
+	if err != nil {
+		log.L.Warnf("[sinks] unable to write event queue: %s", err.Error())
+	}
+}
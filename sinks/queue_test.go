@@ -0,0 +1,99 @@
+package sinks
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/byuoitav/common/events"
+)
+
+func queueTest(t *testing.T, max int) (*QueuedSink, *recorder, func()) {
+	dir, err := ioutil.TempDir("", "sinks")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &recorder{name: "recorder"}
+	return NewQueuedSink(r, filepath.Join(dir, "queue.jsonl"), max, ""), r, func() { os.RemoveAll(dir) }
+}
+
+func send(q *QueuedSink, keys ...string) {
+	for _, key := range keys {
+		q.Send(events.APISuccess, event(events.INFO, key))
+	}
+}
+
+func TestQueueOrder(t *testing.T) {
+	q, r, cleanup := queueTest(t, 10)
+	defer cleanup()
+
+	send(q, "a")
+	r.fail = true
+	send(q, "b", "c")
+
+	//still down, so nothing moves
+	q.replay()
+	if q.Depth() != 2 {
+		t.Errorf("expected 2 events queued, got %v", q.Depth())
+	}
+
+	//once it's back, new events still wait behind the queued ones
+	r.fail = false
+	send(q, "d")
+	if keys(r.sent) != "a" {
+		t.Errorf("expected d to wait for the queue, got %s", keys(r.sent))
+	}
+
+	q.replay()
+	if keys(r.sent) != "a,b,c,d" {
+		t.Errorf("expected a,b,c,d, got %s", keys(r.sent))
+	}
+
+	send(q, "e")
+	if keys(r.sent) != "a,b,c,d,e" || q.Depth() != 0 {
+		t.Errorf("expected e to be sent straight away, got %s with %v queued", keys(r.sent), q.Depth())
+	}
+}
+
+func TestQueueMax(t *testing.T) {
+	q, r, cleanup := queueTest(t, 3)
+	defer cleanup()
+
+	r.fail = true
+	send(q, "a", "b", "c", "d", "e")
+	if q.Depth() != 3 {
+		t.Errorf("expected 3 events queued, got %v", q.Depth())
+	}
+
+	r.fail = false
+	q.replay()
+	if keys(r.sent) != "c,d,e" {
+		t.Errorf("expected the oldest to be dropped, got %s", keys(r.sent))
+	}
+}
+
+func TestQueueReload(t *testing.T) {
+	q, r, cleanup := queueTest(t, 10)
+	defer cleanup()
+
+	r.fail = true
+	send(q, "a", "b", "c")
+
+	//the first sink's gone, as if the Pi restarted
+	restarted := &recorder{name: "restarted"}
+	q = NewQueuedSink(restarted, q.path, 2, "")
+	if q.Depth() != 2 {
+		t.Errorf("expected 2 events to be loaded, got %v", q.Depth())
+	}
+
+	q.replay()
+	if keys(restarted.sent) != "b,c" {
+		t.Errorf("expected b,c to be replayed, got %s", keys(restarted.sent))
+	}
+
+	if _, err := os.Stat(q.path); !os.IsNotExist(err) {
+		t.Error("expected the queue file to be removed once it's empty")
+	}
+}
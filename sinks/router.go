@@ -0,0 +1,31 @@
+package sinks
+
+import (
+	"errors"
+
+	"github.com/byuoitav/common/events"
+)
+
+// RouterSink sends events to the event router.
+type RouterSink struct {
+	node *events.EventNode
+}
+
+// NewRouterSink returns a sink that publishes through node.
+func NewRouterSink(node *events.EventNode) *RouterSink {
+	return &RouterSink{node: node}
+}
+
+// Name fulfills the Sink interface.
+func (r *RouterSink) Name() string {
+	return Router
+}
+
+// Send fulfills the Sink interface.
+func (r *RouterSink) Send(header string, e events.Event) error {
+	if r.node == nil {
+		return errors.New("no event node")
+	}
+
+	return r.node.PublishEvent(header, e)
+}
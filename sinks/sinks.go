@@ -0,0 +1,242 @@
+package sinks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/byuoitav/common/events"
+	"github.com/byuoitav/common/log"
+)
+
+// The kinds of sink that can be configured.
+const (
+	Router  = "router"
+	Webhook = "webhook"
+	File    = "file"
+	Stdout  = "stdout"
+)
+
+// Sink is somewhere events are sent. The header is events.APISuccess or events.APIError.
+type Sink interface {
+	Name() string
+	Send(header string, e events.Event) error
+}
+
+// Config describes one sink. Types limits the sink to those event types (e.g. "ERROR", "CORESTATE"); an empty list sends it everything.
+type Config struct {
+	Type  string   `json:"type"`
+	Types []string `json:"types,omitempty"`
+
+	//webhook
+	URL           string `json:"url,omitempty"`
+	BatchSize     int    `json:"batch-size,omitempty"`
+	FlushInterval string `json:"flush-interval,omitempty"`
+
+	//file
+	Path     string `json:"path,omitempty"`
+	MaxSize  int64  `json:"max-size,omitempty"`
+	MaxFiles int    `json:"max-files,omitempty"`
+}
+
+var typeNames = map[string]events.EventType{
+	"ERROR":       events.ERROR,
+	"CORESTATE":   events.CORESTATE,
+	"DETAILSTATE": events.DETAILSTATE,
+	"USERACTION":  events.USERACTION,
+	"INFO":        events.INFO,
+	"HEALTH":      events.HEALTH,
+}
+
+// filtered only passes on the event types its sink was configured for.
+type filtered struct {
+	Sink
+	types map[events.EventType]bool
+}
+
+func (f *filtered) Send(header string, e events.Event) error {
+	if len(f.types) > 0 && !f.types[e.Event.Type] {
+		return nil
+	}
+
+	return f.Sink.Send(header, e)
+}
+
+// Load builds the sinks described in the file at EVENT_SINKS_PATH, or else the comma separated list of
+// sink types in EVENT_SINKS. Without either, events only go to the event router, through node.
+func Load(node *events.EventNode) ([]Sink, error) {
+	var configs []Config
+
+	if path := os.Getenv("EVENT_SINKS_PATH"); len(path) > 0 {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read event sinks: %s", err.Error())
+		}
+
+		var c struct {
+			Sinks []Config `json:"sinks"`
+		}
+
+		err = json.Unmarshal(b, &c)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse event sinks: %s", err.Error())
+		}
+
+		configs = c.Sinks
+	} else if list := os.Getenv("EVENT_SINKS"); len(list) > 0 {
+		listed := make(map[string]bool)
+		for _, kind := range strings.Split(list, ",") {
+			kind = strings.ToLower(strings.TrimSpace(kind))
+
+			//every sink of a type would get the same settings, so there's no point in listing it twice
+			if listed[kind] {
+				return nil, fmt.Errorf("%s is listed more than once in EVENT_SINKS; use EVENT_SINKS_PATH to configure more than one", kind)
+			}
+			listed[kind] = true
+
+			configs = append(configs, envConfig(kind))
+		}
+	} else {
+		configs = []Config{{Type: Router}}
+	}
+
+	var sinks []Sink
+	for _, config := range configs {
+		sink, err := New(config, node)
+		if err != nil {
+			return nil, err
+		}
+
+		log.L.Infof("[sinks] sending %s events to %s", typesString(config.Types), sink.Name())
+		sinks = append(sinks, sink)
+	}
+
+	return sinks, nil
+}
+
+//envConfig builds the config for a sink listed in EVENT_SINKS. Its settings come from EVENT_<TYPE>_*,
+//e.g. EVENT_WEBHOOK_TYPES=ERROR,HEALTH.
+func envConfig(kind string) Config {
+	prefix := "EVENT_" + strings.ToUpper(kind) + "_"
+
+	config := Config{
+		Type: kind,
+		URL:  os.Getenv("EVENT_WEBHOOK_URL"),
+		Path: os.Getenv("EVENT_FILE_PATH"),
+	}
+
+	if types := os.Getenv(prefix + "TYPES"); len(types) > 0 {
+		for _, t := range strings.Split(types, ",") {
+			config.Types = append(config.Types, strings.TrimSpace(t))
+		}
+	}
+
+	return config
+}
+
+// New builds a single sink from its config.
+func New(config Config, node *events.EventNode) (Sink, error) {
+	var sink Sink
+
+	switch strings.ToLower(config.Type) {
+	case Router:
+		sink = NewRouterSink(node)
+
+		//without a router there's nothing to wait for
+		address := os.Getenv("EVENT_ROUTER_ADDRESS")
+		if len(address) == 0 {
+			break
+		}
+
+		path := os.Getenv("EVENT_QUEUE_PATH")
+		if len(path) == 0 {
+			path = DefaultQueuePath
+		}
+
+		sink = NewQueuedSink(sink, path, GetQueueMax(), address)
+	case Webhook:
+		if len(config.URL) == 0 {
+			return nil, errors.New("a webhook sink needs a url")
+		}
+
+		interval, err := time.ParseDuration(config.FlushInterval)
+		if err != nil {
+			interval = DefaultFlushInterval
+		}
+
+		sink = NewWebhookSink(config.URL, config.BatchSize, interval)
+	case File:
+		path := config.Path
+		if len(path) == 0 {
+			path = DefaultFilePath
+		}
+
+		sink = NewFileSink(path, config.MaxSize, config.MaxFiles)
+	case Stdout:
+		sink = NewStdoutSink()
+	default:
+		return nil, fmt.Errorf("unknown event sink type %s", config.Type)
+	}
+
+	if len(config.Types) == 0 {
+		return sink, nil
+	}
+
+	f := &filtered{Sink: sink, types: make(map[events.EventType]bool)}
+	for _, name := range config.Types {
+		t, ok := typeNames[strings.ToUpper(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown event type %s for the %s sink", name, config.Type)
+		}
+
+		f.types[t] = true
+	}
+
+	return f, nil
+}
+
+// Publish sends an event to every sink. A sink that fails doesn't keep the event from the others.
+func Publish(sinks []Sink, header string, e events.Event) error {
+	var failures []string
+	for _, sink := range sinks {
+		err := sink.Send(header, e)
+		if err != nil {
+			log.L.Warnf("[sinks] unable to send event to %s: %s", sink.Name(), err.Error())
+			failures = append(failures, fmt.Sprintf("%s: %s", sink.Name(), err.Error()))
+		}
+	}
+
+	if len(failures) > 0 {
+		return errors.New(strings.Join(failures, "; "))
+	}
+
+	return nil
+}
+
+// QueueDepth returns how many events are waiting to be delivered to any of the sinks.
+func QueueDepth(sinks []Sink) int {
+	depth := 0
+	for _, sink := range sinks {
+		if f, ok := sink.(*filtered); ok {
+			sink = f.Sink
+		}
+
+		if q, ok := sink.(*QueuedSink); ok {
+			depth += q.Depth()
+		}
+	}
+
+	return depth
+}
+
+func typesString(types []string) string {
+	if len(types) == 0 {
+		return "all"
+	}
+
+	return strings.Join(types, ", ")
+}
@@ -0,0 +1,127 @@
+package sinks
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/byuoitav/common/events"
+)
+
+// recorder keeps every event it's sent, or fails them all.
+type recorder struct {
+	name string
+	fail bool
+	sent []events.Event
+}
+
+func (r *recorder) Name() string {
+	return r.name
+}
+
+func (r *recorder) Send(header string, e events.Event) error {
+	if r.fail {
+		return errors.New("unreachable")
+	}
+
+	r.sent = append(r.sent, e)
+	return nil
+}
+
+func event(t events.EventType, key string) events.Event {
+	return events.Event{Event: events.EventInfo{Type: t, EventInfoKey: key}}
+}
+
+func keys(sent []events.Event) string {
+	var k []string
+	for _, e := range sent {
+		k = append(k, e.Event.EventInfoKey)
+	}
+
+	return strings.Join(k, ",")
+}
+
+func TestFiltered(t *testing.T) {
+	tests := []struct {
+		name     string
+		types    []string
+		expected string
+	}{
+		{"everything", nil, "error,state,health"},
+		{"one type", []string{"ERROR"}, "error"},
+		{"case doesn't matter", []string{"error", "Health"}, "error,health"},
+	}
+
+	for _, test := range tests {
+		sink, err := New(Config{Type: Stdout, Types: test.types}, nil)
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+
+		r := &recorder{name: "recorder"}
+		if f, ok := sink.(*filtered); ok {
+			f.Sink = r
+			sink = f
+		} else {
+			sink = r
+		}
+
+		for _, e := range []events.Event{event(events.ERROR, "error"), event(events.CORESTATE, "state"), event(events.HEALTH, "health")} {
+			sink.Send(events.APISuccess, e)
+		}
+
+		if keys(r.sent) != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.name, test.expected, keys(r.sent))
+		}
+	}
+
+	if _, err := New(Config{Type: Stdout, Types: []string{"WARNING"}}, nil); err == nil {
+		t.Error("expected an unknown event type to fail")
+	}
+}
+
+func TestPublish(t *testing.T) {
+	working, broken := &recorder{name: "working"}, &recorder{name: "broken", fail: true}
+
+	err := Publish([]Sink{broken, working}, events.APISuccess, event(events.INFO, "info"))
+	if err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("expected the broken sink to be reported, got %v", err)
+	}
+
+	if keys(working.sent) != "info" {
+		t.Errorf("expected the working sink to still get the event, got %s", keys(working.sent))
+	}
+}
+
+func TestLoadFromEnv(t *testing.T) {
+	defer os.Unsetenv("EVENT_SINKS")
+	defer os.Unsetenv("EVENT_STDOUT_TYPES")
+
+	os.Setenv("EVENT_SINKS", "stdout, file")
+	os.Setenv("EVENT_STDOUT_TYPES", "ERROR, HEALTH")
+
+	sinks, err := Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sinks) != 2 {
+		t.Fatalf("expected 2 sinks, got %v", len(sinks))
+	}
+
+	f, ok := sinks[0].(*filtered)
+	if !ok || len(f.types) != 2 || !f.types[events.ERROR] || !f.types[events.HEALTH] {
+		t.Errorf("expected stdout to only get errors and health, got %+v", sinks[0])
+	}
+
+	if _, ok := sinks[1].(*filtered); ok {
+		t.Error("expected the file sink to get everything")
+	}
+
+	os.Setenv("EVENT_SINKS", "stdout,Stdout")
+	if _, err := Load(nil); err == nil {
+		t.Error("expected a sink listed twice to fail")
+	}
+}
@@ -0,0 +1,39 @@
+package sinks
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/byuoitav/common/events"
+)
+
+// StdoutSink writes each event to stdout as a line of JSON.
+type StdoutSink struct {
+	mutex sync.Mutex
+}
+
+// NewStdoutSink returns a sink that writes to stdout.
+func NewStdoutSink() *StdoutSink {
+	return &StdoutSink{}
+}
+
+// Name fulfills the Sink interface.
+func (s *StdoutSink) Name() string {
+	return Stdout
+}
+
+// Send fulfills the Sink interface.
+func (s *StdoutSink) Send(header string, e events.Event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	_, err = fmt.Fprintln(os.Stdout, string(b))
+	return err
+}
@@ -0,0 +1,125 @@
+package sinks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/byuoitav/common/events"
+	"github.com/byuoitav/common/log"
+)
+
+// DefaultBatchSize is how many events are sent to a webhook at once, if the sink doesn't say.
+const DefaultBatchSize = 50
+
+// DefaultFlushInterval is the longest an event waits for its batch to fill up, if the sink doesn't say.
+const DefaultFlushInterval = 10 * time.Second
+
+// WebhookSink POSTs events to a URL as a JSON array, once enough have built up or the flush interval passes.
+type WebhookSink struct {
+	url       string
+	batchSize int
+	client    *http.Client
+
+	batch []events.Event
+	retry []events.Event
+	mutex sync.Mutex
+}
+
+// NewWebhookSink returns a sink that posts to url. A zero batch size gets the default.
+func NewWebhookSink(url string, batchSize int, interval time.Duration) *WebhookSink {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	w := &WebhookSink{
+		url:       url,
+		batchSize: batchSize,
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		for range ticker.C {
+			err := w.Flush()
+			if err != nil {
+				log.L.Warnf("[sinks] unable to flush events to %s: %s", w.url, err.Error())
+			}
+		}
+	}()
+
+	return w
+}
+
+// Name fulfills the Sink interface.
+func (w *WebhookSink) Name() string {
+	return fmt.Sprintf("%s %s", Webhook, w.url)
+}
+
+// Send fulfills the Sink interface. The event is only posted once its batch is full.
+func (w *WebhookSink) Send(header string, e events.Event) error {
+	w.mutex.Lock()
+	w.batch = append(w.batch, e)
+	full := len(w.batch) >= w.batchSize
+	w.mutex.Unlock()
+
+	if full {
+		return w.Flush()
+	}
+
+	return nil
+}
+
+// Flush posts whatever events are waiting. A batch that can't be posted is kept and tried once more
+// with the next flush; if that fails too it's dropped, so a webhook that's down can't use up all of
+// the Pi's memory.
+func (w *WebhookSink) Flush() error {
+	w.mutex.Lock()
+	retry := w.retry
+	batch := append(append([]events.Event{}, retry...), w.batch...)
+	w.retry = nil
+	w.batch = nil
+	w.mutex.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	err := w.post(batch)
+	if err == nil {
+		return nil
+	}
+
+	//everything that hasn't been retried yet gets one more chance
+	w.mutex.Lock()
+	w.retry = batch[len(retry):]
+	w.mutex.Unlock()
+
+	if len(retry) > 0 {
+		return fmt.Errorf("dropped %v events, will retry %v: %s", len(retry), len(batch)-len(retry), err.Error())
+	}
+
+	return fmt.Errorf("will retry %v events: %s", len(batch), err.Error())
+}
+
+func (w *WebhookSink) post(batch []events.Event) error {
+	b, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+
+	resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("non-2xx response code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
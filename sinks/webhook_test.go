@@ -0,0 +1,56 @@
+package sinks
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/byuoitav/common/events"
+)
+
+func TestWebhookRetry(t *testing.T) {
+	var failing bool
+	var received [][]events.Event
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		var batch []events.Event
+		json.NewDecoder(r.Body).Decode(&batch)
+		received = append(received, batch)
+	}))
+	defer server.Close()
+
+	w := NewWebhookSink(server.URL, 10, time.Hour)
+
+	//the first failure keeps the batch, the second drops it
+	failing = true
+	w.Send(events.APISuccess, event(events.INFO, "a"))
+	if err := w.Flush(); err == nil {
+		t.Error("expected the flush to fail")
+	}
+
+	w.Send(events.APISuccess, event(events.INFO, "b"))
+	if err := w.Flush(); err == nil {
+		t.Error("expected the flush to fail")
+	}
+
+	failing = false
+	w.Send(events.APISuccess, event(events.INFO, "c"))
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(received) != 1 || keys(received[0]) != "b,c" {
+		t.Errorf("expected one batch of b,c, got %v", received)
+	}
+
+	if err := w.Flush(); err != nil || len(received) != 1 {
+		t.Errorf("expected nothing left to flush, got %v, %v", err, received)
+	}
+}
@@ -0,0 +1,54 @@
+package state
+
+import (
+	"github.com/byuoitav/av-api/audit"
+	"github.com/byuoitav/av-api/base"
+	se "github.com/byuoitav/av-api/statusevaluators"
+	"github.com/byuoitav/common/log"
+	"github.com/fatih/color"
+)
+
+//saveAuditRecord fills in the actions generated for a request and their outcomes, then writes the record to the audit log.
+func saveAuditRecord(record audit.Record, actions []base.ActionStructure, responses []se.StatusResponse, err error) {
+
+	record.Finish(err)
+
+	//the first action is the reconciler's starting node, not something that gets executed
+	if len(actions) > 0 {
+		actions = actions[1:]
+	}
+
+	for _, action := range actions {
+		entry := audit.Action{
+			Action:     action.Action,
+			Device:     action.Device.ID,
+			Evaluator:  action.GeneratingEvaluator,
+			Parameters: action.Parameters,
+			Outcome:    audit.OutcomeNotRun,
+		}
+
+		if action.Overridden {
+			entry.Outcome = audit.OutcomeOverridden
+		}
+
+		for _, response := range responses {
+			if response.Action != action.Action || response.SourceDevice.ID != action.Device.ID {
+				continue
+			}
+
+			entry.Outcome = audit.OutcomeSuccess
+			if response.ErrorMessage != nil {
+				entry.Outcome = audit.OutcomeFailure
+				entry.Error = *response.ErrorMessage
+			}
+			break
+		}
+
+		record.Actions = append(record.Actions, entry)
+	}
+
+	err = audit.GetStore().Append(record)
+	if err != nil {
+		log.L.Errorf("%s", color.HiRedString("[state] unable to write audit record %s: %s", record.ID, err.Error()))
+	}
+}
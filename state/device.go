@@ -0,0 +1,131 @@
+package state
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/av-api/helpers"
+	se "github.com/byuoitav/av-api/statusevaluators"
+	"github.com/byuoitav/common/db"
+	"github.com/byuoitav/common/log"
+	"github.com/byuoitav/common/structs"
+	"github.com/fatih/color"
+)
+
+//GetDeviceState assesses the state of a single device in a room, only running the status commands that target it.
+func GetDeviceState(building, roomName, deviceName string) (base.PublicDevice, error) {
+
+	log.L.Infof("%s", color.HiCyanString("[state] getting state of %s in %s-%s...", deviceName, building, roomName))
+
+	roomID := fmt.Sprintf("%v-%v", building, roomName)
+	room, err := db.GetDB().GetRoom(roomID)
+	if err != nil {
+		return base.PublicDevice{}, helpers.DatabaseError(err, helpers.UnknownRoom, roomID)
+	}
+
+	device, err := findDevice(room, deviceName)
+	if err != nil {
+		return base.PublicDevice{}, err
+	}
+
+	deviceCommands, err := GenerateDeviceStatusCommands(room, device, se.StatusEvaluatorMap)
+	if err != nil {
+		return base.PublicDevice{}, err
+	}
+
+	if len(deviceCommands) == 0 {
+		return base.PublicDevice{}, helpers.NewError(helpers.ConfigError, "no status commands found for %s", device.ID).WithDetail("device", device.Name)
+	}
+
+	responses, err := RunStatusCommands(deviceCommands)
+	if err != nil {
+		return base.PublicDevice{}, err
+	}
+
+	status, err := EvaluateResponses(responses, len(deviceCommands))
+	if err != nil {
+		return base.PublicDevice{}, err
+	}
+
+	return DeviceFromRoom(status, device.Name), nil
+}
+
+//DeviceRequest turns a request to change a single device into a request to change the room it's in,
+//so that it goes through the same evaluators.
+func DeviceRequest(building, roomName, deviceName string, target base.PublicDevice) (base.PublicRoom, error) {
+
+	deviceID := fmt.Sprintf("%v-%v-%v", building, roomName, deviceName)
+	device, err := db.GetDB().GetDevice(deviceID)
+	if err != nil {
+		return base.PublicRoom{}, helpers.DatabaseError(err, helpers.UnknownDevice, deviceID)
+	}
+
+	request := base.PublicRoom{
+		Building: building,
+		Room:     roomName,
+	}
+
+	display := base.Display{Device: base.Device{Name: device.Name}, Blanked: target.Blanked}
+	audioDevice := base.AudioDevice{Device: base.Device{Name: device.Name}, Muted: target.Muted, Volume: target.Volume}
+
+	//power and input only need to go to one of the lists, or they'll be evaluated twice
+	if structs.HasRole(device, "VideoOut") || !structs.HasRole(device, "AudioOut") {
+		display.Power = target.Power
+		display.Input = target.Input
+	} else {
+		audioDevice.Power = target.Power
+		audioDevice.Input = target.Input
+	}
+
+	if len(display.Power) > 0 || len(display.Input) > 0 || display.Blanked != nil {
+		request.Displays = []base.Display{display}
+	}
+
+	if len(audioDevice.Power) > 0 || len(audioDevice.Input) > 0 || audioDevice.Muted != nil || audioDevice.Volume != nil {
+		request.AudioDevices = []base.AudioDevice{audioDevice}
+	}
+
+	return request, nil
+}
+
+//DeviceFromRoom pulls the state of a single device out of the state of its room.
+func DeviceFromRoom(room base.PublicRoom, deviceName string) base.PublicDevice {
+	output := base.PublicDevice{Device: base.Device{Name: deviceName}}
+
+	for _, display := range room.Displays {
+		if strings.EqualFold(display.Name, deviceName) {
+			output.Device = display.Device
+			output.Blanked = display.Blanked
+			output.Info = display.Info
+		}
+	}
+
+	for _, audioDevice := range room.AudioDevices {
+		if strings.EqualFold(audioDevice.Name, deviceName) {
+			if len(output.Power) == 0 {
+				output.Power = audioDevice.Power
+			}
+			if len(output.Input) == 0 {
+				output.Input = audioDevice.Input
+			}
+			output.Muted = audioDevice.Muted
+			output.Volume = audioDevice.Volume
+			if output.Info == nil {
+				output.Info = audioDevice.Info
+			}
+		}
+	}
+
+	return output
+}
+
+func findDevice(room structs.Room, name string) (structs.Device, error) {
+	for _, device := range room.Devices {
+		if strings.EqualFold(device.Name, name) {
+			return device, nil
+		}
+	}
+
+	return structs.Device{}, helpers.NewError(helpers.UnknownDevice, "no device %s in room %s", name, room.ID).WithDetail("device", name)
+}
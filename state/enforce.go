@@ -0,0 +1,360 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/av-api/locks"
+	ei "github.com/byuoitav/common/events"
+	"github.com/byuoitav/common/log"
+	"github.com/fatih/color"
+)
+
+// The policies for a field that has drifted from the desired state.
+const (
+	//DriftReapply sets the field back to what it should be.
+	DriftReapply = "reapply"
+	//DriftEvent only publishes a drift event.
+	DriftEvent = "event"
+	//DriftIgnore does nothing.
+	DriftIgnore = "ignore"
+)
+
+//EnforcerRequestor is the requestor the enforcer uses when it reapplies state.
+const EnforcerRequestor = "desired-state-enforcer"
+
+//desiredRoom is the last state each device in a room was successfully set to.
+type desiredRoom struct {
+	building     string
+	room         string
+	displays     map[string]base.Display
+	audioDevices map[string]base.AudioDevice
+}
+
+var desired = make(map[string]*desiredRoom)
+var desiredMutex sync.Mutex
+
+//drifting is the fields that had drifted in each room the last time it was checked, and what they had drifted to.
+//Only the enforcer uses it.
+var drifting = make(map[string]map[string]string)
+
+var driftPolicies map[string]string
+
+//EnforcementEnabled reports whether the enforcement loop should run: only on a room's Pi, and only if ENFORCE_INTERVAL is set.
+func EnforcementEnabled() bool {
+	_, err := time.ParseDuration(os.Getenv("ENFORCE_INTERVAL"))
+	return len(os.Getenv("LOCAL_ENVIRONMENT")) > 0 && err == nil
+}
+
+//LoadDriftPolicies reads the policy for each field from the file at ENFORCE_POLICY_PATH. Without it, every field gets DriftEvent.
+func LoadDriftPolicies() error {
+	path := os.Getenv("ENFORCE_POLICY_PATH")
+	if len(path) == 0 {
+		return nil
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read drift policies: %s", err.Error())
+	}
+
+	var policies map[string]string
+	err = json.Unmarshal(b, &policies)
+	if err != nil {
+		return fmt.Errorf("unable to parse drift policies in %s: %s", path, err.Error())
+	}
+
+	for field, policy := range policies {
+		switch strings.ToLower(policy) {
+		case DriftReapply, DriftEvent, DriftIgnore:
+		default:
+			return fmt.Errorf("unknown drift policy %s for %s in %s", policy, field, path)
+		}
+	}
+
+	driftPolicies = policies
+	log.L.Infof("[enforce] loaded drift policies from %s: %v", path, driftPolicies)
+	return nil
+}
+
+//getDriftPolicy returns the policy for a field that LoadDriftPolicies read, or DriftEvent.
+func getDriftPolicy(field string) string {
+	policy, ok := driftPolicies[field]
+	if !ok {
+		return DriftEvent
+	}
+
+	return strings.ToLower(policy)
+}
+
+//rememberDesired records the state a request asked each device in the report to be set to, on top of what earlier requests set.
+//The report decides which devices were set, and target what they were set to. A device with an action that failed isn't
+//remembered, so the enforcer doesn't keep sending it something it has already rejected.
+func rememberDesired(target, report base.PublicRoom) {
+	if !EnforcementEnabled() {
+		return
+	}
+
+	failed := make(map[string]bool)
+	for _, statusError := range report.Errors {
+		failed[strings.ToLower(statusError.Device)] = true
+	}
+
+	desiredMutex.Lock()
+	defer desiredMutex.Unlock()
+
+	roomID := fmt.Sprintf("%v-%v", report.Building, report.Room)
+	d, ok := desired[roomID]
+	if !ok {
+		d = &desiredRoom{
+			building:     report.Building,
+			room:         report.Room,
+			displays:     make(map[string]base.Display),
+			audioDevices: make(map[string]base.AudioDevice),
+		}
+		desired[roomID] = d
+	}
+
+	for _, reported := range report.Displays {
+		if failed[strings.ToLower(reported.Name)] {
+			continue
+		}
+
+		display := desiredDisplay(target, reported.Name)
+		current := d.displays[display.Name]
+		current.Name = display.Name
+		if len(display.Power) > 0 {
+			current.Power = display.Power
+		}
+		if len(display.Input) > 0 {
+			current.Input = display.Input
+		}
+		if display.Blanked != nil {
+			current.Blanked = display.Blanked
+		}
+		d.displays[display.Name] = current
+	}
+
+	for _, reported := range report.AudioDevices {
+		if failed[strings.ToLower(reported.Name)] {
+			continue
+		}
+
+		audioDevice := desiredAudioDevice(target, reported.Name)
+		current := d.audioDevices[audioDevice.Name]
+		current.Name = audioDevice.Name
+		if len(audioDevice.Power) > 0 {
+			current.Power = audioDevice.Power
+		}
+		if len(audioDevice.Input) > 0 {
+			current.Input = audioDevice.Input
+		}
+		if audioDevice.Muted != nil {
+			current.Muted = audioDevice.Muted
+		}
+		if audioDevice.Volume != nil {
+			current.Volume = audioDevice.Volume
+		}
+		d.audioDevices[audioDevice.Name] = current
+	}
+}
+
+//StartEnforcer checks every ENFORCE_INTERVAL that each room is still in the state it was last set to,
+//and handles any drift according to the field's policy. It never returns.
+func StartEnforcer() {
+	interval, _ := time.ParseDuration(os.Getenv("ENFORCE_INTERVAL"))
+	log.L.Infof("%s", color.HiBlueString("[enforce] enforcing desired state every %v", interval))
+
+	ticker := time.NewTicker(interval)
+	for range ticker.C {
+		desiredMutex.Lock()
+		var rooms []desiredRoom
+		for _, d := range desired {
+			rooms = append(rooms, d.copy())
+		}
+		desiredMutex.Unlock()
+
+		for _, d := range rooms {
+			enforce(d)
+		}
+	}
+}
+
+func (d *desiredRoom) copy() desiredRoom {
+	output := desiredRoom{
+		building:     d.building,
+		room:         d.room,
+		displays:     make(map[string]base.Display),
+		audioDevices: make(map[string]base.AudioDevice),
+	}
+
+	for name, display := range d.displays {
+		output.displays[name] = display
+	}
+	for name, audioDevice := range d.audioDevices {
+		output.audioDevices[name] = audioDevice
+	}
+
+	return output
+}
+
+//enforce compares a room to its desired state, then reapplies or reports whatever drifted.
+func enforce(d desiredRoom) {
+	roomID := fmt.Sprintf("%v-%v", d.building, d.room)
+
+	//a room in the middle of changing hasn't drifted, it just isn't done yet
+	if roomBusy(roomID) {
+		log.L.Debugf("[enforce] %s is busy, skipping", roomID)
+		return
+	}
+
+	actual, err := GetRoomState(d.building, d.room)
+	if err != nil {
+		log.L.Warnf("[enforce] unable to get the state of %s: %s", roomID, err.Error())
+		return
+	}
+
+	want := base.PublicRoom{Building: d.building, Room: d.room}
+	for _, display := range d.displays {
+		want.Displays = append(want.Displays, display)
+	}
+	for _, audioDevice := range d.audioDevices {
+		want.AudioDevices = append(want.AudioDevices, audioDevice)
+	}
+
+	var drifted []base.Mismatch
+	for _, display := range want.Displays {
+		for _, current := range actual.Displays {
+			if strings.EqualFold(display.Name, current.Name) {
+				drifted = compareField(drifted, display.Name, "power", display.Power, current.Power)
+				drifted = compareField(drifted, display.Name, "input", display.Input, current.Input)
+				drifted = compareField(drifted, display.Name, "blanked", display.Blanked, current.Blanked)
+			}
+		}
+	}
+	for _, audioDevice := range want.AudioDevices {
+		for _, current := range actual.AudioDevices {
+			if strings.EqualFold(audioDevice.Name, current.Name) {
+				drifted = compareField(drifted, audioDevice.Name, "power", audioDevice.Power, current.Power)
+				drifted = compareField(drifted, audioDevice.Name, "input", audioDevice.Input, current.Input)
+				drifted = compareField(drifted, audioDevice.Name, "muted", audioDevice.Muted, current.Muted)
+				drifted = compareField(drifted, audioDevice.Name, "volume", audioDevice.Volume, current.Volume)
+			}
+		}
+	}
+
+	changed := driftChanges(roomID, drifted)
+
+	reapply := base.PublicRoom{Building: d.building, Room: d.room}
+	for i, drift := range drifted {
+		policy := getDriftPolicy(drift.Field)
+		if policy == DriftIgnore {
+			continue
+		}
+
+		if changed[i] {
+			msg := fmt.Sprintf("%s drifted from %s %s to %s", drift.Device, drift.Field, drift.Desired, drift.Actual)
+			log.L.Warnf("%s", color.HiYellowString("[enforce] %s", msg))
+			base.SendEvent(ei.DETAILSTATE, ei.AUTOGENERATED, drift.Device, d.room, d.building, "drift-"+drift.Field, msg, EnforcerRequestor, false)
+		}
+
+		if policy != DriftReapply {
+			continue
+		}
+
+		//devices someone else holds a lease on are theirs to change
+		err := locks.Check(d.building, d.room, EnforcerRequestor, []string{drift.Device})
+		if err != nil {
+			log.L.Infof("[enforce] leaving %s %s alone: %s", drift.Device, drift.Field, err.Error())
+			continue
+		}
+
+		addDrift(&reapply, d, drift)
+	}
+
+	if len(reapply.Displays) == 0 && len(reapply.AudioDevices) == 0 {
+		return
+	}
+
+	log.L.Infof("%s", color.HiBlueString("[enforce] reapplying desired state to %s", roomID))
+
+	_, err = SetRoomState(reapply, EnforcerRequestor)
+	if err != nil {
+		log.L.Errorf("%s", color.HiRedString("[enforce] unable to reapply desired state to %s: %s", roomID, err.Error()))
+	}
+}
+
+//driftChanges records what drifted in a room, and reports which of the drifts are new since the room was last checked:
+//fields that weren't drifting before, or have drifted somewhere else.
+func driftChanges(roomID string, drifted []base.Mismatch) []bool {
+	previous := drifting[roomID]
+	current := make(map[string]string)
+	changed := make([]bool, len(drifted))
+
+	for i, drift := range drifted {
+		key := drift.Device + "/" + drift.Field
+		current[key] = drift.Actual
+
+		actual, ok := previous[key]
+		changed[i] = !ok || actual != drift.Actual
+	}
+
+	drifting[roomID] = current
+	return changed
+}
+
+//addDrift adds the desired value of a drifted field to the request that puts it back.
+func addDrift(request *base.PublicRoom, d desiredRoom, drift base.Mismatch) {
+	if display, ok := d.displays[drift.Device]; ok && drift.Field != "muted" && drift.Field != "volume" {
+		index := -1
+		for i := range request.Displays {
+			if request.Displays[i].Name == display.Name {
+				index = i
+			}
+		}
+		if index == -1 {
+			request.Displays = append(request.Displays, base.Display{Device: base.Device{Name: display.Name}})
+			index = len(request.Displays) - 1
+		}
+
+		switch drift.Field {
+		case "power":
+			request.Displays[index].Power = display.Power
+		case "input":
+			request.Displays[index].Input = display.Input
+		case "blanked":
+			request.Displays[index].Blanked = display.Blanked
+		}
+		return
+	}
+
+	if audioDevice, ok := d.audioDevices[drift.Device]; ok {
+		index := -1
+		for i := range request.AudioDevices {
+			if request.AudioDevices[i].Name == audioDevice.Name {
+				index = i
+			}
+		}
+		if index == -1 {
+			request.AudioDevices = append(request.AudioDevices, base.AudioDevice{Device: base.Device{Name: audioDevice.Name}})
+			index = len(request.AudioDevices) - 1
+		}
+
+		switch drift.Field {
+		case "power":
+			request.AudioDevices[index].Power = audioDevice.Power
+		case "input":
+			request.AudioDevices[index].Input = audioDevice.Input
+		case "muted":
+			request.AudioDevices[index].Muted = audioDevice.Muted
+		case "volume":
+			request.AudioDevices[index].Volume = audioDevice.Volume
+		}
+	}
+}
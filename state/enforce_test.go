@@ -0,0 +1,50 @@
+package state
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadDriftPolicies(t *testing.T) {
+	dir, err := ioutil.TempDir("", "enforce")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer os.Unsetenv("ENFORCE_POLICY_PATH")
+	defer func() { driftPolicies = nil }()
+
+	tests := []struct {
+		name   string
+		config string
+		fails  bool
+	}{
+		{name: "unknown policy", config: `{"power": "reapply", "input": "revert"}`, fails: true},
+		{name: "malformed", config: `{"power": `, fails: true},
+		{name: "valid", config: `{"power": "Reapply", "volume": "ignore"}`},
+	}
+
+	for _, test := range tests {
+		path := filepath.Join(dir, "policies.json")
+		ioutil.WriteFile(path, []byte(test.config), 0644)
+		os.Setenv("ENFORCE_POLICY_PATH", path)
+
+		err := LoadDriftPolicies()
+		if test.fails != (err != nil) {
+			t.Errorf("%s: expected failure to be %v, got %v", test.name, test.fails, err)
+		}
+	}
+
+	os.Setenv("ENFORCE_POLICY_PATH", filepath.Join(dir, "missing.json"))
+	if err := LoadDriftPolicies(); err == nil {
+		t.Error("expected a missing file to fail")
+	}
+
+	for field, expected := range map[string]string{"power": DriftReapply, "volume": DriftIgnore, "input": DriftEvent} {
+		if policy := getDriftPolicy(field); policy != expected {
+			t.Errorf("expected %s to be %s, got %s", field, expected, policy)
+		}
+	}
+}
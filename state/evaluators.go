@@ -0,0 +1,48 @@
+package state
+
+import (
+	"strings"
+
+	"github.com/byuoitav/av-api/actionreconcilers"
+	ce "github.com/byuoitav/av-api/commandevaluators"
+	"github.com/byuoitav/av-api/registry"
+	"github.com/byuoitav/common/structs"
+)
+
+//ConfigurationUses lists the evaluators and reconciler a room's configuration refers to, for registry.Check.
+//Evaluators defined in the configuration itself, and conflict policies, are checked when they're used.
+func ConfigurationUses(room structs.Room) registry.Uses {
+	uses := registry.Uses{
+		registry.Reconciler: {reconcilerKey(room)},
+	}
+
+	for _, evaluator := range room.Configuration.Evaluators {
+		switch {
+		case strings.HasPrefix(evaluator.CodeKey, actionreconcilers.FLAG),
+			strings.HasPrefix(evaluator.CodeKey, actionreconcilers.ConflictFlag),
+			strings.HasPrefix(evaluator.CodeKey, ce.ConfigFlag):
+		case strings.Contains(evaluator.CodeKey, "STATUS"):
+			uses[registry.Status] = append(uses[registry.Status], evaluator.CodeKey)
+		default:
+			uses[registry.Command] = append(uses[registry.Command], evaluator.CodeKey)
+		}
+	}
+
+	return uses
+}
+
+//statusEvaluatorFor returns the status evaluator that reads the responses to the commands a command evaluator generates.
+//this is where we decide which status evaluator is used to evalutate the resultant status of a command that sets state
+func statusEvaluatorFor(commandEvaluator string) string {
+	entry, ok := registry.Get(registry.Command, commandEvaluator)
+	if ok {
+		return entry.Status
+	}
+
+	//evaluators defined in a room's configuration name theirs in their definition
+	if evaluator, ok := ce.Lookup(commandEvaluator).(*ce.ConfigEvaluator); ok {
+		return evaluator.Status
+	}
+
+	return ""
+}
@@ -0,0 +1,43 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/byuoitav/av-api/registry"
+	"github.com/byuoitav/common/structs"
+)
+
+func TestRegistrations(t *testing.T) {
+	err := registry.Check()
+	if err != nil {
+		t.Errorf("expected the registered evaluators to line up: %s", err)
+	}
+}
+
+func TestConfigurationUses(t *testing.T) {
+	room := structs.Room{Configuration: structs.RoomConfiguration{
+		Description: "Default",
+		Evaluators: []structs.Evaluator{
+			{CodeKey: "PowerOnDefault"},
+			{CodeKey: "UnmuteDSP"},
+			{CodeKey: "STATUS_PowerDefault"},
+			{CodeKey: "RECONCILER_StrictPriority"},
+			{CodeKey: "CONFLICT_Precedence"},
+			{CodeKey: "CONFIG_FreezeDisplay"},
+		},
+	}}
+
+	uses := ConfigurationUses(room)
+	if err := registry.Check(uses); err != nil {
+		t.Errorf("expected the configuration to check out: %s", err)
+	}
+
+	if len(uses[registry.Command]) != 2 || len(uses[registry.Status]) != 1 || uses[registry.Reconciler][0] != "StrictPriority" {
+		t.Errorf("unexpected uses: %v", uses)
+	}
+
+	room.Configuration.Evaluators = append(room.Configuration.Evaluators, structs.Evaluator{CodeKey: "UnMuteDSP"})
+	if err := registry.Check(ConfigurationUses(room)); err == nil {
+		t.Error("expected an evaluator that isn't registered to be reported")
+	}
+}
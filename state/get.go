@@ -1,13 +1,13 @@
 package state
 
 import (
-	"errors"
 	"fmt"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/av-api/helpers"
 	se "github.com/byuoitav/av-api/statusevaluators"
 	"github.com/byuoitav/common/events"
 	"github.com/byuoitav/common/log"
@@ -30,6 +30,9 @@ func GenerateStatusCommands(room structs.Room, commandMap map[string]se.StatusEv
 		if strings.HasPrefix(possibleEvaluator.CodeKey, se.FLAG) {
 
 			currentEvaluator := se.StatusEvaluatorMap[possibleEvaluator.CodeKey]
+			if currentEvaluator == nil {
+				return []se.StatusCommand{}, 0, helpers.NewError(helpers.ConfigError, "no status evaluator corresponding to key: %s", possibleEvaluator.CodeKey).WithDetail("evaluator", possibleEvaluator.CodeKey)
+			}
 
 			//we can get the number of output devices here
 			devices, err := currentEvaluator.GetDevices(room)
@@ -50,13 +53,41 @@ func GenerateStatusCommands(room structs.Room, commandMap map[string]se.StatusEv
 	return output, count, nil
 }
 
+//GenerateDeviceStatusCommands generates the status commands for a single device. The evaluators only see the device,
+//along with the switchers and DSPs its state is read through, so commands aren't built for the rest of the room.
+func GenerateDeviceStatusCommands(room structs.Room, device structs.Device, commandMap map[string]se.StatusEvaluator) ([]se.StatusCommand, error) {
+	scoped := room
+	scoped.Devices = []structs.Device{device}
+	for _, d := range room.Devices {
+		if d.ID != device.ID && (structs.HasRole(d, "VideoSwitcher") || structs.HasRole(d, "DSP")) {
+			scoped.Devices = append(scoped.Devices, d)
+		}
+	}
+
+	commands, _, err := GenerateStatusCommands(scoped, commandMap)
+	if err != nil {
+		return []se.StatusCommand{}, err
+	}
+
+	//the switchers and DSPs are only there to read the device through, so drop anything about them
+	var output []se.StatusCommand
+	for _, command := range commands {
+		if command.DestinationDevice.ID == device.ID {
+			output = append(output, command)
+		}
+	}
+
+	return output, nil
+}
+
 // RunStatusCommands maps the device names to their commands, and then puts them in a channel to be run.
 func RunStatusCommands(commands []se.StatusCommand) (outputs []se.StatusResponse, err error) {
 
 	log.L.Infof("%s", color.HiBlueString("[state] running status commands..."))
 
+	//the room's outputs are still reported, each with an error saying it didn't answer
 	if len(commands) == 0 {
-		err = errors.New("no commands")
+		log.L.Warnf("%s", color.HiYellowString("[state] no status commands to run"))
 		return
 	}
 
@@ -119,28 +150,56 @@ func EvaluateResponses(responses []se.StatusResponse, count int) (base.PublicRoo
 
 	log.L.Infof("%s", color.HiBlueString("[state] Evaluating responses..."))
 
-	if len(responses) == 0 { //make sure things aren't broken
-		msg := "no status responses found"
-		log.L.Errorf("%s", color.HiRedString("[error] %s", msg))
-		return base.PublicRoom{}, errors.New(msg)
+	if len(responses) == 0 {
+		log.L.Warnf("%s", color.HiYellowString("[state] no status responses found"))
+		return base.PublicRoom{}, nil
 	}
 
 	var AudioDevices []base.AudioDevice
 	var Displays []base.Display
+	var Screens, Lifts, Shades []base.Motorized
+	var Errors []base.StatusError
 	doneCount := 0
 
-	//we need to create our return channel
-	returnChan := make(chan base.StatusPackage, len(responses))
+	//we need to create our return channel. a callback can send more than one value per response, but never more than we expect
+	returnChan := make(chan base.StatusPackage, len(responses)+count)
 
 	//make our array of Statuses by device
 	responsesByDestinationDevice := make(map[string]se.Status)
 	for _, resp := range responses {
 
+		//a device that couldn't be queried is still reported, with whatever its other commands could read
+		if resp.ErrorMessage != nil {
+			name := resp.DestinationDevice.Name
+			if len(name) == 0 {
+				name = resp.SourceDevice.Name
+			}
+
+			Errors = append(Errors, base.StatusError{Device: name, Command: resp.Action, Error: *resp.ErrorMessage})
+
+			//it won't send anything, so don't wait for it
+			doneCount++
+
+			if _, ok := responsesByDestinationDevice[resp.DestinationDevice.ID]; !ok && len(resp.DestinationDevice.ID) > 0 {
+				responsesByDestinationDevice[resp.DestinationDevice.ID] = se.Status{
+					Status:            make(map[string]interface{}),
+					DestinationDevice: resp.DestinationDevice,
+				}
+			}
+			continue
+		}
+
 		//we do thing the old fashioned way
 		if resp.Callback == nil {
 			for key, value := range resp.Status {
 				log.L.Infof("[state] Checking generator: %s", resp.Generator)
-				k, v, err := se.StatusEvaluatorMap[resp.Generator].EvaluateResponse(key, value, resp.SourceDevice, resp.DestinationDevice)
+				evaluator, ok := se.StatusEvaluatorMap[resp.Generator]
+				if !ok {
+					log.L.Errorf("%s", color.HiRedString("[state] no status evaluator %s to process the response %v - %v", resp.Generator, key, value))
+					continue
+				}
+
+				k, v, err := evaluator.EvaluateResponse(key, value, resp.SourceDevice, resp.DestinationDevice)
 				if err != nil {
 
 					log.L.Errorf("%s", color.HiRedString("[state] problem procesing the response %v - %v with evaluator %v: %s",
@@ -218,7 +277,66 @@ func EvaluateResponses(responses []se.StatusResponse, count int) (base.PublicRoo
 				Displays = append(Displays, display)
 			}
 		}
+		if v.DestinationDevice.Motorized {
+			motorized := processMotorized(v)
+
+			switch {
+			case structs.HasRole(v.DestinationDevice.Device, base.ScreenRole):
+				Screens = append(Screens, motorized)
+			case structs.HasRole(v.DestinationDevice.Device, base.LiftRole):
+				Lifts = append(Lifts, motorized)
+			case structs.HasRole(v.DestinationDevice.Device, base.ShadeRole):
+				Shades = append(Shades, motorized)
+			}
+		}
+	}
+
+	return base.PublicRoom{Displays: Displays, AudioDevices: AudioDevices, Screens: Screens, Lifts: Lifts, Shades: Shades, Errors: Errors}, nil
+}
+
+//includeAllOutputs makes sure every output device in the room is in its status, even if nothing could be read from it,
+//so a UI can show that the device didn't answer instead of leaving it out.
+func includeAllOutputs(room structs.Room, status *base.PublicRoom) {
+	for _, device := range room.Devices {
+		if structs.HasRole(device, "VideoOut") && !hasDisplay(status.Displays, device.Name) {
+			status.Displays = append(status.Displays, base.Display{Device: base.Device{Name: device.Name}})
+			addNoResponse(status, device.Name)
+		}
+
+		if structs.HasRole(device, "AudioOut") && !hasAudioDevice(status.AudioDevices, device.Name) {
+			status.AudioDevices = append(status.AudioDevices, base.AudioDevice{Device: base.Device{Name: device.Name}})
+			addNoResponse(status, device.Name)
+		}
+	}
+}
+
+func hasDisplay(displays []base.Display, name string) bool {
+	for _, display := range displays {
+		if strings.EqualFold(display.Name, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasAudioDevice(audioDevices []base.AudioDevice, name string) bool {
+	for _, audioDevice := range audioDevices {
+		if strings.EqualFold(audioDevice.Name, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//addNoResponse records that a device didn't report anything, unless a failed query already explains why.
+func addNoResponse(status *base.PublicRoom, name string) {
+	for _, statusError := range status.Errors {
+		if strings.EqualFold(statusError.Device, name) {
+			return
+		}
 	}
 
-	return base.PublicRoom{Displays: Displays, AudioDevices: AudioDevices}, nil
+	status.Errors = append(status.Errors, base.StatusError{Device: name, Error: "no response"})
 }
@@ -0,0 +1,105 @@
+package state
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/byuoitav/av-api/base"
+	se "github.com/byuoitav/av-api/statusevaluators"
+	"github.com/byuoitav/common/structs"
+)
+
+func output(id, name, role string) structs.Device {
+	return structs.Device{ID: id, Name: name, Roles: []structs.Role{{ID: role}}}
+}
+
+func failed(device structs.Device, action, msg string) se.StatusResponse {
+	return se.StatusResponse{
+		Action:            action,
+		SourceDevice:      device,
+		DestinationDevice: base.DestinationDevice{Device: device, Display: true},
+		ErrorMessage:      &msg,
+	}
+}
+
+func TestEvaluateFailedResponses(t *testing.T) {
+	d1 := output("ITB-1101-D1", "D1", "VideoOut")
+	responses := []se.StatusResponse{
+		failed(d1, "STATUS_Power", "connection refused"),
+		failed(d1, "STATUS_Input", "connection refused"),
+	}
+
+	//neither query will send anything, so there's nothing to wait for
+	start := time.Now()
+	status, err := EvaluateResponses(responses, len(responses))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if time.Since(start) > 500*time.Millisecond {
+		t.Errorf("expected failed queries not to be waited on, took %v", time.Since(start))
+	}
+
+	if len(status.Displays) != 1 || status.Displays[0].Name != "D1" {
+		t.Errorf("expected D1 to be reported, got %+v", status.Displays)
+	}
+
+	if len(status.Errors) != 2 || status.Errors[0].Command != "STATUS_Power" || status.Errors[1].Command != "STATUS_Input" {
+		t.Errorf("expected both failed queries, got %+v", status.Errors)
+	}
+}
+
+func TestIncludeAllOutputs(t *testing.T) {
+	room := structs.Room{Devices: []structs.Device{
+		output("ITB-1101-D1", "D1", "VideoOut"),
+		output("ITB-1101-D2", "D2", "VideoOut"),
+		output("ITB-1101-AMP1", "AMP1", "AudioOut"),
+		output("ITB-1101-SW1", "SW1", "VideoSwitcher"),
+	}}
+
+	tests := []struct {
+		name      string
+		responses []se.StatusResponse
+		errors    []string
+	}{
+		{
+			name:      "some answered",
+			responses: []se.StatusResponse{failed(room.Devices[0], "STATUS_Power", "connection refused")},
+			errors:    []string{"D1 connection refused", "D2 no response", "AMP1 no response"},
+		},
+		{
+			name:   "nothing to run",
+			errors: []string{"D1 no response", "D2 no response", "AMP1 no response"},
+		},
+	}
+
+	for _, test := range tests {
+		responses, err := RunStatusCommands(nil)
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+
+		status, err := EvaluateResponses(append(responses, test.responses...), len(test.responses))
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+
+		includeAllOutputs(room, &status)
+
+		if len(status.Displays) != 2 || len(status.AudioDevices) != 1 {
+			t.Errorf("%s: expected every output to be listed, got %+v and %+v", test.name, status.Displays, status.AudioDevices)
+		}
+
+		var errors []string
+		for _, e := range status.Errors {
+			errors = append(errors, e.Device+" "+e.Error)
+		}
+
+		if strings.Join(errors, ",") != strings.Join(test.errors, ",") {
+			t.Errorf("%s: expected errors %v, got %v", test.name, test.errors, errors)
+		}
+	}
+}
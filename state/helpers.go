@@ -2,9 +2,9 @@ package state
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"os"
 	"strings"
@@ -14,6 +14,7 @@ import (
 	"github.com/byuoitav/authmiddleware/bearertoken"
 	"github.com/byuoitav/av-api/base"
 	"github.com/byuoitav/av-api/gateway"
+	"github.com/byuoitav/av-api/helpers"
 	se "github.com/byuoitav/av-api/statusevaluators"
 	ei "github.com/byuoitav/common/events"
 	"github.com/byuoitav/common/log"
@@ -42,6 +43,7 @@ func issueCommands(commands []se.StatusCommand, channel chan []se.StatusResponse
 		output := se.StatusResponse{
 			Callback:          command.Callback,
 			Generator:         command.Generator,
+			Action:            command.Action.ID,
 			SourceDevice:      command.Device,
 			DestinationDevice: command.DestinationDevice,
 		}
@@ -53,6 +55,8 @@ func issueCommands(commands []se.StatusCommand, channel chan []se.StatusResponse
 			msg := fmt.Sprintf("unable to replace paramaters for %s: %s", command.Action.ID, err.Error())
 			log.L.Errorf("%s", color.HiRedString("[error] %s", msg))
 			base.PublishError(msg, ei.INTERNAL)
+			output.ErrorMessage = &msg
+			outputs = append(outputs, output)
 			continue
 		}
 
@@ -63,6 +67,8 @@ func issueCommands(commands []se.StatusCommand, channel chan []se.StatusResponse
 			msg := fmt.Sprintf("unable to set gateway for %s: %s", command.Action.ID, err.Error())
 			log.L.Errorf("%s", color.HiRedString("[error] %s", msg))
 			base.PublishError(msg, ei.INTERNAL)
+			output.ErrorMessage = &msg
+			outputs = append(outputs, output)
 			continue
 		}
 
@@ -94,6 +100,8 @@ func issueCommands(commands []se.StatusCommand, channel chan []se.StatusResponse
 			msg := fmt.Sprintf("non-200 response code: %d, message: %s", response.StatusCode, string(body))
 			log.L.Errorf("%s", color.HiRedString("[error] %s", msg))
 			base.PublishError(msg, ei.INTERNAL)
+			output.ErrorMessage = &msg
+			outputs = append(outputs, output)
 			continue
 		}
 
@@ -177,6 +185,27 @@ func processAudioDevice(device se.Status) (base.AudioDevice, error) {
 		audioDevice.Input = inputString
 	}
 
+	//wireless mics also report on their batteries and signal
+	battery, ok := device.Status["battery"]
+	batteryInt, ok := battery.(int)
+	if ok {
+		audioDevice.Battery = &batteryInt
+	}
+
+	charging, ok := device.Status["charging"]
+	chargingBool, ok := charging.(bool)
+	if ok {
+		audioDevice.Charging = &chargingBool
+	}
+
+	signal, ok := device.Status["rfSignal"]
+	signalInt, ok := signal.(int)
+	if ok {
+		audioDevice.RFSignal = &signalInt
+	}
+
+	audioDevice.Info = processInfo(device)
+
 	audioDevice.Name = device.DestinationDevice.Name
 	return audioDevice, nil
 }
@@ -205,11 +234,50 @@ func processDisplay(device se.Status) (base.Display, error) {
 		display.Input = inputString
 	}
 
+	display.Info = processInfo(device)
 	display.Name = device.DestinationDevice.Name
 
 	return display, nil
 }
 
+func processMotorized(device se.Status) base.Motorized {
+
+	log.L.Infof("Adding motorized device: %s", device.DestinationDevice.Name)
+
+	var motorized base.Motorized
+
+	state, ok := device.Status["state"]
+	stateString, ok := state.(string)
+	if ok {
+		motorized.State = stateString
+	}
+
+	position, ok := device.Status["position"]
+	positionInt, ok := position.(int)
+	if ok {
+		motorized.Position = &positionInt
+	}
+
+	motorized.Name = device.DestinationDevice.Name
+	return motorized
+}
+
+//processInfo collects the extended status fields of a device into its info
+func processInfo(device se.Status) base.Info {
+	var info base.Info
+
+	for key, value := range device.Status {
+		if strings.HasPrefix(key, se.InfoPrefix) {
+			if info == nil {
+				info = make(base.Info)
+			}
+			info[strings.TrimPrefix(key, se.InfoPrefix)] = value
+		}
+	}
+
+	return info
+}
+
 //ExecuteCommand makes a GET request given a microservice and endpoint and publishes the results
 //returns the state the microservice reports or nothing if the microservice doesn't respond
 //publishes a state event or an error
@@ -219,27 +287,29 @@ func ExecuteCommand(action base.ActionStructure, command structs.Command, endpoi
 	client := &http.Client{
 		Timeout: TIMEOUT * time.Second,
 	}
+
+	//what we report if the command doesn't make it to the device
+	failure := se.StatusResponse{
+		SourceDevice:      action.Device,
+		DestinationDevice: action.DestinationDevice,
+		Action:            action.Action,
+	}
+
 	//set the gateway
 	url, err := gateway.SetGateway(command.Microservice.Address+endpoint, action.Device)
 	if err != nil {
 		msg := fmt.Sprintf("unable to reach gated device: %s: %s", action.Device.Name, err.Error())
-		return se.StatusResponse{ErrorMessage: &msg}
+		failure.ErrorMessage = &msg
+		return failure
 	}
 
 	log.L.Infof("%s", color.HiBlueString("[state] sending request to %s...", url))
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := newCommandRequest(url)
 	if err != nil {
 		msg := err.Error()
-		return se.StatusResponse{ErrorMessage: &msg}
-	}
-
-	if len(os.Getenv("LOCAL_ENVIRONMENT")) == 0 {
-		token, err := bearertoken.GetToken()
-		if err != nil {
-			return se.StatusResponse{}
-		}
-		req.Header.Set("Authorization", "Bearer "+token.Token)
+		failure.ErrorMessage = &msg
+		return failure
 	}
 
 	resp, err := client.Do(req)
@@ -247,7 +317,8 @@ func ExecuteCommand(action base.ActionStructure, command structs.Command, endpoi
 		msg := fmt.Sprintf("error sending request: %s", err.Error())
 		log.L.Errorf("%s", color.HiRedString("[error] %s", msg))
 		PublishError(msg, action, requestor)
-		return se.StatusResponse{ErrorMessage: &msg}
+		failure.ErrorMessage = &msg
+		return failure
 	}
 
 	defer resp.Body.Close()
@@ -264,7 +335,9 @@ func ExecuteCommand(action base.ActionStructure, command structs.Command, endpoi
 		log.L.Errorf("%s", color.HiRedString("[error] microservice returned: %s for action %s against device %s.", b, action.Action, action.Device.Name))
 		PublishError(fmt.Sprintf("%s", b), action, requestor)
 
-		return se.StatusResponse{}
+		msg := fmt.Sprintf("non-200 response code: %d, message: %s", resp.StatusCode, b)
+		failure.ErrorMessage = &msg
+		return failure
 
 	}
 
@@ -302,7 +375,8 @@ func ExecuteCommand(action base.ActionStructure, command structs.Command, endpoi
 	response := se.StatusResponse{
 		SourceDevice:      action.Device,
 		DestinationDevice: action.DestinationDevice,
-		Generator:         SET_STATE_STATUS_EVALUATORS[action.GeneratingEvaluator],
+		Generator:         statusEvaluatorFor(action.GeneratingEvaluator),
+		Action:            action.Action,
 		Status:            status,
 		Callback:          action.Callback,
 	}
@@ -311,6 +385,33 @@ func ExecuteCommand(action base.ActionStructure, command structs.Command, endpoi
 
 }
 
+//newCommandRequest builds the request to send a command to a microservice, with a bearer token if we aren't running in a room.
+func newCommandRequest(url string) (*http.Request, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(os.Getenv("LOCAL_ENVIRONMENT")) == 0 {
+		token, err := bearertoken.GetToken()
+		if err != nil {
+			return nil, fmt.Errorf("unable to get bearer token: %s", err.Error())
+		}
+		req.Header.Set("Authorization", "Bearer "+token.Token)
+	}
+
+	return req, nil
+}
+
+//sendError describes a request to a device's microservice that didn't get a response.
+func sendError(deviceName string, err error) *helpers.Error {
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return helpers.NewError(helpers.Timeout, "%s didn't respond in time: %s", deviceName, err.Error()).WithDetail("device", deviceName)
+	}
+
+	return helpers.NewError(helpers.DeviceUnreachable, "unable to reach %s: %s", deviceName, err.Error()).WithDetail("device", deviceName)
+}
+
 /*
 ReplaceIPAddressEndpoint is a simple helper
 */
@@ -336,7 +437,7 @@ func ReplaceParameters(endpoint string, parameters map[string]string) (string, e
 		if !strings.Contains(endpoint, toReplace) {
 			msg := fmt.Sprintf("%s not found", toReplace)
 			log.L.Errorf("%s", color.HiRedString("[error] %s", msg))
-			return "", errors.New(msg)
+			return "", helpers.NewError(helpers.InvalidRequest, "%s", msg).WithDetail("parameter", k)
 		}
 
 		endpoint = strings.Replace(endpoint, toReplace, v, -1)
@@ -348,7 +449,7 @@ func ReplaceParameters(endpoint string, parameters map[string]string) (string, e
 
 		if strings.Contains(endpoint[index+1:], ":") {
 			errorString := fmt.Sprintf("not enough parameters provided for command: %s", endpoint) //TODO change this setup?
-			return "", errors.New(errorString)
+			return "", helpers.NewError(helpers.InvalidRequest, "%s", errorString)
 		}
 	}
 
@@ -0,0 +1,48 @@
+package state
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	ce "github.com/byuoitav/av-api/commandevaluators"
+	"github.com/byuoitav/av-api/gateway"
+	"github.com/byuoitav/av-api/helpers"
+	"github.com/byuoitav/common/structs"
+)
+
+//ProbeDevice sends a status command to a device through its gateway, and reports how long it took to answer.
+//Nothing is published, so it's safe to call as often as a health check needs to.
+func ProbeDevice(device structs.Device, commandID string) (time.Duration, error) {
+	has, command := ce.CheckCommands(device.Type.Commands, commandID)
+	if !has {
+		return 0, helpers.NewError(helpers.ConfigError, "%s has no command %s", device.ID, commandID).WithDetail("device", device.Name)
+	}
+
+	endpoint := ReplaceIPAddressEndpoint(command.Endpoint.Path, device.Address)
+	url, err := gateway.SetStatusGateway(command.Microservice.Address+endpoint, device)
+	if err != nil {
+		return 0, helpers.Wrap(err, fmt.Sprintf("unable to reach gated device: %s", device.Name))
+	}
+
+	req, err := newCommandRequest(url)
+	if err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+
+	client := &http.Client{Timeout: TIMEOUT * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, sendError(device.Name, err)
+	}
+	resp.Body.Close()
+
+	latency := time.Since(start)
+	if resp.StatusCode != http.StatusOK {
+		return latency, helpers.NewError(helpers.DeviceRejected, "non-200 response code: %d", resp.StatusCode).WithDetail("device", device.Name).WithDetail("status", resp.StatusCode)
+	}
+
+	return latency, nil
+}
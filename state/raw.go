@@ -0,0 +1,141 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/byuoitav/av-api/audit"
+	"github.com/byuoitav/av-api/base"
+	ce "github.com/byuoitav/av-api/commandevaluators"
+	"github.com/byuoitav/av-api/gateway"
+	"github.com/byuoitav/av-api/helpers"
+	"github.com/byuoitav/common/db"
+	ei "github.com/byuoitav/common/events"
+	"github.com/byuoitav/common/log"
+	"github.com/fatih/color"
+)
+
+// RawResponse is exactly what a microservice returned for a raw command.
+type RawResponse struct {
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+// RawEvaluator is recorded in the audit log as the evaluator behind a raw command.
+const RawEvaluator = "raw-command"
+
+// ExecuteRawCommand sends any of a device's commands straight to its microservice, bypassing the evaluators,
+// but still going through the device's gateway, waiting its turn to change the room, and publishing an event.
+// Every raw command is written to the audit log.
+func ExecuteRawCommand(building, room, deviceName, commandID string, parameters map[string]string, requestor string) (response RawResponse, err error) {
+
+	log.L.Infof("%s", color.HiBlueString("[state] executing raw command %s against %s-%s-%s...", commandID, building, room, deviceName))
+
+	deviceID := fmt.Sprintf("%v-%v-%v", building, room, deviceName)
+
+	record := audit.NewRecord(base.PublicRoom{Building: building, Room: room}, requestor)
+	action := audit.Action{
+		Action:     commandID,
+		Device:     deviceID,
+		Evaluator:  RawEvaluator,
+		Parameters: parameters,
+		Outcome:    audit.OutcomeNotRun,
+	}
+	defer func() {
+		if err == nil && response.StatusCode != http.StatusOK {
+			action.Outcome = audit.OutcomeFailure
+			action.Error = string(response.Body)
+		}
+
+		record.Actions = []audit.Action{action}
+		record.Finish(err)
+
+		err := audit.GetStore().Append(record)
+		if err != nil {
+			log.L.Errorf("%s", color.HiRedString("[state] unable to write audit record %s: %s", record.ID, err.Error()))
+		}
+	}()
+
+	device, err := db.GetDB().GetDevice(deviceID)
+	if err != nil {
+		return RawResponse{}, helpers.DatabaseError(err, helpers.UnknownDevice, deviceID)
+	}
+
+	has, command := ce.CheckCommands(device.Type.Commands, commandID)
+	if !has {
+		return RawResponse{}, helpers.NewError(helpers.InvalidRequest, "%s has no command %s", device.ID, commandID).WithDetail("command", commandID)
+	}
+
+	//the parameters come straight from the caller, so they can't be allowed to change the shape of the path
+	escaped := make(map[string]string)
+	for k, v := range parameters {
+		escaped[k] = url.PathEscape(v)
+	}
+
+	endpoint := ReplaceIPAddressEndpoint(command.Endpoint.Path, device.Address)
+	endpoint, err = ReplaceParameters(endpoint, escaped)
+	if err != nil {
+		return RawResponse{}, err
+	}
+
+	//a raw command is a change to the room like any other, so it waits its turn
+	exec, done, err := beginExecution(fmt.Sprintf("%v-%v", building, room))
+	if err != nil {
+		return RawResponse{}, err
+	}
+	defer done()
+
+	if exec.superseded() {
+		return RawResponse{}, ErrSuperseded
+	}
+
+	address, err := gateway.SetGateway(command.Microservice.Address+endpoint, device)
+	if err != nil {
+		return RawResponse{}, helpers.Wrap(err, fmt.Sprintf("unable to reach gated device: %s", device.Name))
+	}
+
+	req, err := newCommandRequest(address)
+	if err != nil {
+		return RawResponse{}, err
+	}
+
+	log.L.Infof("%s", color.HiBlueString("[state] sending raw request to %s...", address))
+
+	client := &http.Client{Timeout: TIMEOUT * time.Second}
+	resp, err := client.Do(req)
+	action.Outcome = audit.OutcomeFailure
+	if err != nil {
+		msg := fmt.Sprintf("error sending request: %s", err.Error())
+		log.L.Errorf("%s", color.HiRedString("[error] %s", msg))
+		base.SendEvent(ei.ERROR, ei.USERINPUT, device.Name, room, building, commandID, msg, requestor, true)
+		return RawResponse{}, sendError(device.Name, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return RawResponse{}, fmt.Errorf("could not read response body: %s", err.Error())
+	}
+
+	if resp.StatusCode == http.StatusOK {
+		action.Outcome = audit.OutcomeSuccess
+	}
+
+	params, _ := json.Marshal(parameters)
+	if resp.StatusCode == http.StatusOK {
+		base.SendEvent(ei.USERACTION, ei.USERINPUT, device.Name, room, building, commandID, string(params), requestor, false)
+	} else {
+		base.SendEvent(ei.ERROR, ei.USERINPUT, device.Name, room, building, commandID, string(body), requestor, true)
+	}
+
+	return RawResponse{
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        body,
+	}, nil
+}
@@ -0,0 +1,259 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/byuoitav/av-api/base"
+	ce "github.com/byuoitav/av-api/commandevaluators"
+	"github.com/byuoitav/av-api/gateway"
+	"github.com/byuoitav/av-api/helpers"
+	"github.com/byuoitav/common/log"
+	"github.com/fatih/color"
+)
+
+//DefaultReadyTimeout and DefaultReadyInterval are used for ready conditions that don't set their own.
+const (
+	DefaultReadyTimeout  = 30 * time.Second
+	DefaultReadyInterval = 2 * time.Second
+)
+
+//Sequence is what to wait for after a command, before the actions that depend on it are run.
+//Delay is how long to wait after the command is sent. Ready, if set, is then polled until it's met or times out.
+type Sequence struct {
+	Delay string          `json:"delay,omitempty"`
+	Ready *ReadyCondition `json:"ready,omitempty"`
+}
+
+//ReadyCondition is met once the status command reports Value in Field, e.g. STATUS_Power reporting "on" in "power".
+type ReadyCondition struct {
+	Command  string `json:"command"`
+	Field    string `json:"field"`
+	Value    string `json:"value"`
+	Timeout  string `json:"timeout,omitempty"`
+	Interval string `json:"interval,omitempty"`
+}
+
+//sequencing holds the sequences by command, and by device type then command. A device type's sequence wins.
+type sequencing struct {
+	Commands    map[string]Sequence            `json:"commands"`
+	DeviceTypes map[string]map[string]Sequence `json:"deviceTypes"`
+}
+
+var sequences sequencing
+
+//LoadSequencing reads and checks the sequences in the file at SEQUENCING_PATH. There's no sequencing if it isn't set.
+func LoadSequencing() error {
+	path := os.Getenv("SEQUENCING_PATH")
+	if len(path) == 0 {
+		return nil
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read sequencing config: %s", err.Error())
+	}
+
+	var config sequencing
+	err = json.Unmarshal(b, &config)
+	if err != nil {
+		return fmt.Errorf("unable to parse sequencing config in %s: %s", path, err.Error())
+	}
+
+	err = config.validate()
+	if err != nil {
+		return fmt.Errorf("invalid sequencing config in %s: %s", path, err.Error())
+	}
+
+	sequences = config
+	log.L.Infof("[state] loaded sequencing for %v commands and %v device types from %s", len(sequences.Commands), len(sequences.DeviceTypes), path)
+	return nil
+}
+
+//validate makes sure every duration can be parsed, and every ready condition says what to wait for.
+func (s sequencing) validate() error {
+	for command, sequence := range s.Commands {
+		if err := sequence.validate(); err != nil {
+			return fmt.Errorf("%s: %s", command, err.Error())
+		}
+	}
+
+	for deviceType, byCommand := range s.DeviceTypes {
+		for command, sequence := range byCommand {
+			if err := sequence.validate(); err != nil {
+				return fmt.Errorf("%s %s: %s", deviceType, command, err.Error())
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s Sequence) validate() error {
+	if err := checkDuration("delay", s.Delay); err != nil {
+		return err
+	}
+
+	if s.Ready == nil {
+		return nil
+	}
+
+	if len(s.Ready.Command) == 0 || len(s.Ready.Field) == 0 {
+		return fmt.Errorf("a ready condition needs a command and a field")
+	}
+
+	if err := checkDuration("timeout", s.Ready.Timeout); err != nil {
+		return err
+	}
+
+	return checkDuration("interval", s.Ready.Interval)
+}
+
+//checkDuration makes sure a configured duration, if it's set, is a positive time.Duration.
+func checkDuration(name, duration string) error {
+	if len(duration) == 0 {
+		return nil
+	}
+
+	d, err := time.ParseDuration(duration)
+	if err != nil {
+		return fmt.Errorf("invalid %s %s: %s", name, duration, err.Error())
+	}
+
+	if d <= 0 {
+		return fmt.Errorf("%s %s must be positive", name, duration)
+	}
+
+	return nil
+}
+
+//getSequence finds the sequence LoadSequencing read for an action, if there is one.
+func getSequence(action base.ActionStructure) (Sequence, bool) {
+	if byCommand, ok := sequences.DeviceTypes[action.Device.Type.ID]; ok {
+		if sequence, ok := byCommand[action.Action]; ok {
+			return sequence, true
+		}
+	}
+
+	sequence, ok := sequences.Commands[action.Action]
+	return sequence, ok
+}
+
+//waitForSequence holds up the actions that depend on action until its device is ready for them.
+//It gives up early if the request is cancelled, and carries on (with an error event) if the device never gets ready.
+func waitForSequence(action base.ActionStructure, requestor string, cancel <-chan struct{}) {
+	sequence, ok := getSequence(action)
+	if !ok {
+		return
+	}
+
+	//LoadSequencing has already made sure the delay is valid
+	if len(sequence.Delay) > 0 {
+		delay, _ := time.ParseDuration(sequence.Delay)
+		log.L.Infof("[state] waiting %v after %s on %s", delay, action.Action, action.Device.Name)
+
+		select {
+		case <-time.After(delay):
+		case <-cancel:
+			return
+		}
+	}
+
+	if sequence.Ready == nil {
+		return
+	}
+
+	err := waitUntilReady(action, *sequence.Ready, cancel)
+	if err != nil {
+		msg := fmt.Sprintf("%s never became ready after %s: %s", action.Device.Name, action.Action, err.Error())
+		log.L.Warnf("%s", color.HiYellowString("[state] %s", msg))
+		PublishError(msg, action, requestor)
+	}
+}
+
+//waitUntilReady polls the ready condition's status command until it reports the value, the timeout passes, or the request is cancelled.
+func waitUntilReady(action base.ActionStructure, ready ReadyCondition, cancel <-chan struct{}) error {
+	timeout := parseDurationOr(ready.Timeout, DefaultReadyTimeout)
+	interval := parseDurationOr(ready.Interval, DefaultReadyInterval)
+
+	has, command := ce.CheckCommands(action.Device.Type.Commands, ready.Command)
+	if !has {
+		return helpers.NewError(helpers.ConfigError, "%s has no command %s", action.Device.ID, ready.Command)
+	}
+
+	endpoint, err := ReplaceParameters(command.Endpoint.Path, map[string]string{"address": action.Device.Address})
+	if err != nil {
+		return err
+	}
+
+	url, err := gateway.SetStatusGateway(command.Microservice.Address+endpoint, action.Device)
+	if err != nil {
+		return err
+	}
+
+	log.L.Infof("[state] waiting up to %v for %s to report %s %s", timeout, action.Device.Name, ready.Field, ready.Value)
+
+	deadline := time.After(timeout)
+	for {
+		value, err := queryField(url, ready.Field)
+		if err != nil {
+			log.L.Debugf("[state] %s isn't ready yet: %s", action.Device.Name, err.Error())
+		} else if value == ready.Value {
+			log.L.Infof("[state] %s is ready", action.Device.Name)
+			return nil
+		}
+
+		select {
+		case <-time.After(interval):
+		case <-deadline:
+			return helpers.NewError(helpers.Timeout, "timed out after %v, last reported %s", timeout, value).WithDetail("device", action.Device.Name)
+		case <-cancel:
+			return fmt.Errorf("cancelled by a newer request")
+		}
+	}
+}
+
+//queryField gets one field of a status response, as a string.
+func queryField(url, field string) (string, error) {
+	req, err := newCommandRequest(url)
+	if err != nil {
+		return "", err
+	}
+
+	client := &http.Client{Timeout: TIMEOUT * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", helpers.NewError(helpers.DeviceRejected, "non-200 response code: %d", resp.StatusCode)
+	}
+
+	var status map[string]interface{}
+	err = json.NewDecoder(resp.Body).Decode(&status)
+	if err != nil {
+		return "", err
+	}
+
+	value, ok := status[field]
+	if !ok {
+		return "", fmt.Errorf("no %s in response", field)
+	}
+
+	return fmt.Sprintf("%v", value), nil
+}
+
+func parseDurationOr(duration string, fallback time.Duration) time.Duration {
+	d, err := time.ParseDuration(duration)
+	if err != nil || d <= 0 {
+		return fallback
+	}
+
+	return d
+}
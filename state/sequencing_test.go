@@ -0,0 +1,56 @@
+package state
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/common/structs"
+)
+
+func TestLoadSequencing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sequencing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer os.Unsetenv("SEQUENCING_PATH")
+	defer func() { sequences = sequencing{} }()
+
+	tests := []struct {
+		name   string
+		config string
+		fails  bool
+	}{
+		{name: "valid", config: `{"commands": {"PowerOn": {"delay": "2s"}}, "deviceTypes": {"SonyVPL": {"PowerOn": {"ready": {"command": "STATUS_Power", "field": "power", "value": "on", "timeout": "45s"}}}}}`},
+		{name: "malformed", config: `{"commands": `, fails: true},
+		{name: "bad delay", config: `{"commands": {"PowerOn": {"delay": "2 seconds"}}}`, fails: true},
+		{name: "negative timeout", config: `{"commands": {"PowerOn": {"ready": {"command": "STATUS_Power", "field": "power", "timeout": "-1s"}}}}`, fails: true},
+		{name: "bad interval for a device type", config: `{"deviceTypes": {"SonyVPL": {"PowerOn": {"ready": {"command": "STATUS_Power", "field": "power", "interval": "often"}}}}}`, fails: true},
+		{name: "ready without a command", config: `{"commands": {"PowerOn": {"ready": {"field": "power", "value": "on"}}}}`, fails: true},
+	}
+
+	for _, test := range tests {
+		path := filepath.Join(dir, "sequencing.json")
+		ioutil.WriteFile(path, []byte(test.config), 0644)
+		os.Setenv("SEQUENCING_PATH", path)
+
+		err := LoadSequencing()
+		if test.fails != (err != nil) {
+			t.Errorf("%s: expected failure to be %v, got %v", test.name, test.fails, err)
+		}
+	}
+
+	os.Setenv("SEQUENCING_PATH", filepath.Join(dir, "missing.json"))
+	if err := LoadSequencing(); err == nil {
+		t.Error("expected a missing file to fail")
+	}
+
+	//the valid config is the one that was kept
+	action := base.ActionStructure{Action: "PowerOn", Device: structs.Device{Type: structs.DeviceType{ID: "SonyVPL"}}}
+	if sequence, ok := getSequence(action); !ok || sequence.Ready == nil || sequence.Ready.Timeout != "45s" {
+		t.Errorf("expected the device type's sequence, got %+v", sequence)
+	}
+}
@@ -0,0 +1,140 @@
+package state
+
+import (
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/byuoitav/av-api/helpers"
+	"github.com/byuoitav/common/log"
+	"github.com/fatih/color"
+)
+
+// The policies for handling a request to change a room that is already being changed.
+const (
+	//PolicyQueue waits for the earlier request to finish.
+	PolicyQueue = "queue"
+	//PolicyReject turns the new request away.
+	PolicyReject = "reject"
+	//PolicySupersede cancels whatever the earlier request hasn't done yet, then runs the new one.
+	PolicySupersede = "supersede"
+)
+
+//ErrRoomBusy is returned under the reject policy when another request is already changing the room.
+var ErrRoomBusy error = helpers.NewError(helpers.Conflict, "another request is already changing the state of this room")
+
+//ErrSuperseded is returned when a newer request cancelled the remaining actions of this one.
+var ErrSuperseded error = helpers.NewError(helpers.Conflict, "this request was superseded by a newer request before all of its actions were run")
+
+//execution is a request that is currently changing the state of a room.
+type execution struct {
+	cancel     chan struct{}
+	cancelOnce sync.Once
+}
+
+func (e *execution) supersede() {
+	e.cancelOnce.Do(func() {
+		close(e.cancel)
+	})
+}
+
+func (e *execution) superseded() bool {
+	select {
+	case <-e.cancel:
+		return true
+	default:
+		return false
+	}
+}
+
+//roomSlot only lets one execution at a time into a room.
+type roomSlot struct {
+	token   chan struct{}
+	current *execution
+	latest  int
+}
+
+var roomSlots = make(map[string]*roomSlot)
+var roomSlotsMutex sync.Mutex
+
+//GetSerializationPolicy returns the policy set in ROOM_SERIALIZATION_POLICY, or PolicyQueue if it isn't set to something we know.
+func GetSerializationPolicy() string {
+	policy := strings.ToLower(os.Getenv("ROOM_SERIALIZATION_POLICY"))
+
+	switch policy {
+	case PolicyQueue, PolicyReject, PolicySupersede:
+		return policy
+	case "":
+		return PolicyQueue
+	default:
+		log.L.Warnf("[state] unknown serialization policy %s, using %s", policy, PolicyQueue)
+		return PolicyQueue
+	}
+}
+
+//beginExecution waits for its turn to change the room, according to the serialization policy.
+//The returned function must be called once the execution is done.
+func beginExecution(roomID string) (*execution, func(), error) {
+
+	roomSlotsMutex.Lock()
+	slot, ok := roomSlots[roomID]
+	if !ok {
+		slot = &roomSlot{token: make(chan struct{}, 1)}
+		roomSlots[roomID] = slot
+	}
+
+	policy := GetSerializationPolicy()
+	if policy == PolicySupersede && slot.current != nil {
+		log.L.Infof("%s", color.HiYellowString("[state] superseding the request in progress on %s", roomID))
+		slot.current.supersede()
+	}
+
+	slot.latest++
+	turn := slot.latest
+	roomSlotsMutex.Unlock()
+
+	if policy == PolicyReject {
+		select {
+		case slot.token <- struct{}{}:
+		default:
+			log.L.Infof("%s", color.HiYellowString("[state] rejecting request, %s is busy", roomID))
+			return nil, nil, ErrRoomBusy
+		}
+	} else {
+		slot.token <- struct{}{}
+	}
+
+	exec := &execution{cancel: make(chan struct{})}
+
+	roomSlotsMutex.Lock()
+	if policy == PolicySupersede && turn != slot.latest {
+		//a newer request came in while we were waiting, so there's no point in starting
+		roomSlotsMutex.Unlock()
+		<-slot.token
+		return nil, nil, ErrSuperseded
+	}
+
+	slot.current = exec
+	roomSlotsMutex.Unlock()
+
+	done := func() {
+		roomSlotsMutex.Lock()
+		if slot.current == exec {
+			slot.current = nil
+		}
+		roomSlotsMutex.Unlock()
+
+		<-slot.token
+	}
+
+	return exec, done, nil
+}
+
+//roomBusy reports whether a request is changing the room right now.
+func roomBusy(roomID string) bool {
+	roomSlotsMutex.Lock()
+	defer roomSlotsMutex.Unlock()
+
+	slot, ok := roomSlots[roomID]
+	return ok && len(slot.token) > 0
+}
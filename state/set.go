@@ -1,7 +1,6 @@
 package state
 
 import (
-	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -9,6 +8,8 @@ import (
 	"github.com/byuoitav/av-api/actionreconcilers"
 	"github.com/byuoitav/av-api/base"
 	ce "github.com/byuoitav/av-api/commandevaluators"
+	"github.com/byuoitav/av-api/helpers"
+	"github.com/byuoitav/av-api/registry"
 	se "github.com/byuoitav/av-api/statusevaluators"
 	"github.com/byuoitav/common/log"
 	"github.com/byuoitav/common/structs"
@@ -16,31 +17,47 @@ import (
 )
 
 //GenerateActions evaluates and validates each command in the configuration.
-func GenerateActions(dbRoom structs.Room, bodyRoom base.PublicRoom, requestor string) ([]base.ActionStructure, int, error) {
+//Incompatible actions are resolved with the room's conflict policy, and each conflict is returned.
+func GenerateActions(dbRoom structs.Room, bodyRoom base.PublicRoom, requestor string) ([]base.ActionStructure, []base.Conflict, int, error) {
 
 	log.L.Infof("%s", color.HiBlueString("[state] generating actions..."))
 
 	var count int
 
+	err := registry.Check(ConfigurationUses(dbRoom))
+	if err != nil {
+		return []base.ActionStructure{}, nil, 0, helpers.NewError(helpers.ConfigError, "%s", err.Error())
+	}
+
 	var output []base.ActionStructure
 	for _, evaluator := range dbRoom.Configuration.Evaluators {
 
-		if strings.Contains(evaluator.CodeKey, "STATUS") {
+		if strings.Contains(evaluator.CodeKey, "STATUS") || strings.HasPrefix(evaluator.CodeKey, actionreconcilers.FLAG) || strings.HasPrefix(evaluator.CodeKey, actionreconcilers.ConflictFlag) {
 			continue
 		}
 
 		//base.Log("[state] considering evaluator %s", evaluator.CodeKey)
 
 		curEvaluator := ce.EVALUATORS[evaluator.CodeKey]
+		if strings.HasPrefix(evaluator.CodeKey, ce.ConfigFlag) {
+			configured, err := ce.FromConfiguration(evaluator)
+			if err != nil {
+				log.L.Errorf("%s", color.HiRedString("[error] %s", err.Error()))
+				return []base.ActionStructure{}, nil, 0, err
+			}
+
+			curEvaluator = configured
+		}
+
 		if curEvaluator == nil {
 			msg := fmt.Sprintf("no evaluator corresponding to key: %s", evaluator.CodeKey)
 			log.L.Errorf("%s", color.HiRedString("[error] %s", msg))
-			return []base.ActionStructure{}, 0, errors.New(msg)
+			return []base.ActionStructure{}, nil, 0, helpers.NewError(helpers.ConfigError, "%s", msg).WithDetail("evaluator", evaluator.CodeKey)
 		}
 
 		actions, c, err := curEvaluator.Evaluate(bodyRoom, requestor)
 		if err != nil {
-			return []base.ActionStructure{}, 0, err
+			return []base.ActionStructure{}, nil, 0, err
 		}
 
 		for _, action := range actions {
@@ -48,7 +65,7 @@ func GenerateActions(dbRoom structs.Room, bodyRoom base.PublicRoom, requestor st
 			if err != nil {
 				msg := fmt.Sprintf("action %s not valid with evaluator %s: %s", action.Action, evaluator.CodeKey, err.Error())
 				log.L.Errorf("%s", color.HiRedString("[error] %s", msg))
-				return []base.ActionStructure{}, 0, errors.New(msg)
+				return []base.ActionStructure{}, nil, 0, helpers.NewError(helpers.ConfigError, "%s", msg).WithDetail("evaluator", evaluator.CodeKey)
 			}
 
 			// Provide a map from the generating evaluator to the generated action in
@@ -62,9 +79,20 @@ func GenerateActions(dbRoom structs.Room, bodyRoom base.PublicRoom, requestor st
 
 	log.L.Infof("%s", color.HiBlueString("[state] generated %v total actions.", len(output)))
 
+	generated := len(output)
+
+	policy, precedence := conflictPolicy(dbRoom)
+	output, conflicts, err := actionreconcilers.ResolveConflicts(output, policy, precedence)
+	if err != nil {
+		return []base.ActionStructure{}, nil, 0, err
+	}
+
+	//the dropped actions won't report a status
+	count -= generated - len(output)
+
 	batches, count, err := ReconcileActions(dbRoom, output, count)
 
-	return batches, count, err
+	return batches, conflicts, count, err
 }
 
 //ReconcileActions produces a DAG
@@ -75,9 +103,11 @@ func ReconcileActions(room structs.Room, actions []base.ActionStructure, inCount
 	//Initialize map of strings to commandevaluators
 	reconcilers := actionreconcilers.Init()
 
-	curReconciler := reconcilers[room.Configuration.Description]
+	key := reconcilerKey(room)
+
+	curReconciler := reconcilers[key]
 	if curReconciler == nil {
-		err = fmt.Errorf("no reconciler corresponding to key: %s ", room.Configuration.Description)
+		err = helpers.NewError(helpers.ConfigError, "no reconciler corresponding to key: %s ", key)
 		return
 	}
 
@@ -91,14 +121,39 @@ func ReconcileActions(room structs.Room, actions []base.ActionStructure, inCount
 	return
 }
 
+//reconcilerKey finds the reconciler a room's configuration asks for, with a RECONCILER_ evaluator.
+//Configurations that don't have one use the reconciler named by their description.
+func reconcilerKey(room structs.Room) string {
+	for _, evaluator := range room.Configuration.Evaluators {
+		if strings.HasPrefix(evaluator.CodeKey, actionreconcilers.FLAG) {
+			return strings.TrimPrefix(evaluator.CodeKey, actionreconcilers.FLAG)
+		}
+	}
+
+	return room.Configuration.Description
+}
+
+//conflictPolicy finds the conflict policy a room's configuration asks for, with a CONFLICT_ evaluator, or ConflictReject.
+//The evaluator's description holds the room's precedence order, for the Precedence policy.
+func conflictPolicy(room structs.Room) (string, []string) {
+	for _, evaluator := range room.Configuration.Evaluators {
+		if strings.HasPrefix(evaluator.CodeKey, actionreconcilers.ConflictFlag) {
+			return strings.TrimPrefix(evaluator.CodeKey, actionreconcilers.ConflictFlag), actionreconcilers.ParsePrecedence(evaluator.Description)
+		}
+	}
+
+	return actionreconcilers.ConflictReject, nil
+}
+
 //ExecuteActions carries out the actions defined in the struct
+//any action that hasn't started by the time cancel is closed is skipped
 //@pre TODO DestinationDevice field is populated for every action!!
-func ExecuteActions(DAG []base.ActionStructure, requestor string) ([]se.StatusResponse, error) {
+func ExecuteActions(DAG []base.ActionStructure, requestor string, cancel <-chan struct{}) ([]se.StatusResponse, error) {
 
 	log.L.Infof("%s", color.HiBlueString("[state] executing actions..."))
 
 	if len(DAG) == 0 {
-		return []se.StatusResponse{}, errors.New("no actions generated")
+		return []se.StatusResponse{}, helpers.NewError(helpers.InvalidRequest, "no actions generated")
 	}
 
 	var output []se.StatusResponse
@@ -109,7 +164,7 @@ func ExecuteActions(DAG []base.ActionStructure, requestor string) ([]se.StatusRe
 	for _, child := range DAG[0].Children {
 
 		done.Add(1)
-		go ExecuteAction(*child, responses, &done, requestor)
+		go ExecuteAction(*child, responses, &done, requestor, cancel)
 	}
 
 	log.L.Info("[state] waiting for responses...")
@@ -132,7 +187,7 @@ func ExecuteActions(DAG []base.ActionStructure, requestor string) ([]se.StatusRe
 }
 
 //ExecuteAction builds a status response
-func ExecuteAction(action base.ActionStructure, responses chan<- se.StatusResponse, control *sync.WaitGroup, requestor string) {
+func ExecuteAction(action base.ActionStructure, responses chan<- se.StatusResponse, control *sync.WaitGroup, requestor string, cancel <-chan struct{}) {
 
 	log.L.Infof("[state] Executing action %s against device %s...", action.Action, action.Device.Name)
 
@@ -143,11 +198,21 @@ func ExecuteAction(action base.ActionStructure, responses chan<- se.StatusRespon
 		return
 	}
 
+	select {
+	case <-cancel:
+		log.L.Infof("%s", color.HiYellowString("[state] Action %s on device %s was cancelled by a newer request.", action.Action, action.Device.Name))
+		responses <- failedAction(action, "cancelled by a newer request")
+		control.Done()
+		return
+	default:
+	}
+
 	has, cmd := ce.CheckCommands(action.Device.Type.Commands, action.Action)
 	if !has {
 		errorStr := fmt.Sprintf("[state] Error retrieving the command %s for device %s.", action.Action, action.Device.ID)
 		log.L.Error(errorStr)
 		PublishError(errorStr, action, requestor)
+		responses <- failedAction(action, errorStr)
 		control.Done()
 		return
 	}
@@ -158,6 +223,7 @@ func ExecuteAction(action base.ActionStructure, responses chan<- se.StatusRespon
 		msg := fmt.Sprintf("Error building endpoint for command %s against device %s: %s", action.Action, action.Device.ID, err.Error())
 		log.L.Errorf("%s", color.HiRedString("[state] %s", msg))
 		PublishError(msg, action, requestor)
+		responses <- failedAction(action, msg)
 		control.Done()
 		return
 	}
@@ -169,34 +235,34 @@ func ExecuteAction(action base.ActionStructure, responses chan<- se.StatusRespon
 	responses <- status
 	log.L.Infof("[state] microservice reported status: %v", status.Status)
 
+	//give the device time to get ready for whatever depends on this action
+	if status.ErrorMessage == nil && len(action.Children) > 0 {
+		waitForSequence(action, requestor, cancel)
+	}
+
 	for _, child := range action.Children {
 
+		//a child of more than one action waits for all of them
+		if child.Join != nil && !child.Join.Arrive() {
+			log.L.Infof("[state] %s on %s is still waiting on other actions", child.Action, child.Device.Name)
+			continue
+		}
+
 		log.L.Infof("[state] found child: %s. Executing...", child.Action)
 
 		control.Add(1)
-		go ExecuteAction(*child, responses, control, requestor)
+		go ExecuteAction(*child, responses, control, requestor, cancel)
 	}
 
 	control.Done()
 }
 
-//SET_STATE_STATUS_EVALUATORS is the map containing the definitions of our evaluator strings.
-//this is where we decide which status evaluator is used to evalutate the resultant status of a command that sets state
-var SET_STATE_STATUS_EVALUATORS = map[string]string{
-
-	"PowerOnDefault":                 "STATUS_PowerDefault",
-	"StandbyDefault":                 "STATUS_PowerDefault",
-	"ChangeVideoInputDefault":        "STATUS_InputDefault",
-	"ChangeAudioInputDefault":        "STATUS_InputDefault",
-	"ChangeVideoInputVideoSwitcher":  "STATUS_InputVideoSwitcher",
-	"ChangeVideoInputTieredSwitcher": "STATUS_InputVideoSwitcher",
-	"BlankDisplayDefault":            "STATUS_BlankedDefault",
-	"UnBlankDisplayDefault":          "STATUS_BlankedDefault",
-	"MuteDefault":                    "STATUS_MutedDefault",
-	"UnMuteDefault":                  "STATUS_MutedDefault",
-	"SetVolumeDefault":               "STATUS_VolumeDefault",
-	"SetVolumeTecLite":               "STATUS_VolumeDefault",
-	"MuteDSP":                        "STATUS_MutedDSP",
-	"UnmuteDSP":                      "STATUS_MutedDSP",
-	"SetVolumeDSP":                   "STATUS_VolumeDSP",
+//failedAction builds the response for an action that couldn't be sent to its device.
+func failedAction(action base.ActionStructure, msg string) se.StatusResponse {
+	return se.StatusResponse{
+		SourceDevice:      action.Device,
+		DestinationDevice: action.DestinationDevice,
+		Action:            action.Action,
+		ErrorMessage:      &msg,
+	}
 }
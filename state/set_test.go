@@ -0,0 +1,32 @@
+package state
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/byuoitav/av-api/actionreconcilers"
+	"github.com/byuoitav/common/structs"
+)
+
+func TestConflictPolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		evaluators []structs.Evaluator
+		policy     string
+		precedence []string
+	}{
+		{"none", []structs.Evaluator{{CodeKey: "PowerOnDefault"}}, actionreconcilers.ConflictReject, nil},
+		{"default precedence", []structs.Evaluator{{CodeKey: "CONFLICT_Precedence"}}, actionreconcilers.ConflictPrecedence, actionreconcilers.DefaultPrecedence},
+		{"the room's own precedence", []structs.Evaluator{{CodeKey: "CONFLICT_Precedence", Description: "PowerOn, Mute"}}, actionreconcilers.ConflictPrecedence, []string{"PowerOn", "Mute"}},
+		{"another policy", []structs.Evaluator{{CodeKey: "CONFLICT_DropBoth"}}, actionreconcilers.ConflictDropBoth, actionreconcilers.DefaultPrecedence},
+	}
+
+	for _, test := range tests {
+		room := structs.Room{Configuration: structs.RoomConfiguration{Evaluators: test.evaluators}}
+
+		policy, precedence := conflictPolicy(room)
+		if policy != test.policy || strings.Join(precedence, ",") != strings.Join(test.precedence, ",") {
+			t.Errorf("%s: expected %s %v, got %s %v", test.name, test.policy, test.precedence, policy, precedence)
+		}
+	}
+}
@@ -3,8 +3,10 @@ package state
 import (
 	"fmt"
 
+	"github.com/byuoitav/av-api/audit"
 	"github.com/byuoitav/av-api/base"
-	"github.com/byuoitav/av-api/statusevaluators"
+	"github.com/byuoitav/av-api/helpers"
+	se "github.com/byuoitav/av-api/statusevaluators"
 	"github.com/byuoitav/common/db"
 	"github.com/byuoitav/common/log"
 	"github.com/fatih/color"
@@ -20,11 +22,11 @@ func GetRoomState(building string, roomName string) (base.PublicRoom, error) {
 	roomID := fmt.Sprintf("%v-%v", building, roomName)
 	room, err := db.GetDB().GetRoom(roomID)
 	if err != nil {
-		return base.PublicRoom{}, err
+		return base.PublicRoom{}, helpers.DatabaseError(err, helpers.UnknownRoom, roomID)
 	}
 
 	//we get the number of actions generated
-	commands, count, err := GenerateStatusCommands(room, statusevaluators.StatusEvaluatorMap)
+	commands, count, err := GenerateStatusCommands(room, se.StatusEvaluatorMap)
 	if err != nil {
 		return base.PublicRoom{}, err
 	}
@@ -39,6 +41,8 @@ func GetRoomState(building string, roomName string) (base.PublicRoom, error) {
 		return base.PublicRoom{}, err
 	}
 
+	includeAllOutputs(room, &roomStatus)
+
 	roomStatus.Building = building
 	roomStatus.Room = roomName
 
@@ -50,35 +54,61 @@ func GetRoomState(building string, roomName string) (base.PublicRoom, error) {
 }
 
 //SetRoomState changes the state of the room and returns a PublicRoom object.
-func SetRoomState(target base.PublicRoom, requestor string) (base.PublicRoom, error) {
+func SetRoomState(target base.PublicRoom, requestor string) (report base.PublicRoom, err error) {
 
 	log.L.Infof("%s", color.HiBlueString("[state] setting room state..."))
 
+	var actions []base.ActionStructure
+	var responses []se.StatusResponse
+
+	record := audit.NewRecord(target, requestor)
+	defer func() {
+		saveAuditRecord(record, actions, responses, err)
+	}()
+
 	roomID := fmt.Sprintf("%v-%v", target.Building, target.Room)
 	room, err := db.GetDB().GetRoom(roomID)
+	if err != nil {
+		return base.PublicRoom{}, helpers.DatabaseError(err, helpers.UnknownRoom, roomID)
+	}
+
+	//only one request at a time gets to change the room
+	exec, done, err := beginExecution(roomID)
 	if err != nil {
 		return base.PublicRoom{}, err
 	}
+	defer done()
 
 	//so here we need to know how many things we're actually expecting.
-	actions, count, err := GenerateActions(room, target, requestor)
+	actions, conflicts, count, err := GenerateActions(room, target, requestor)
 	if err != nil {
 		return base.PublicRoom{}, err
 	}
 
-	responses, err := ExecuteActions(actions, requestor)
+	responses, err = ExecuteActions(actions, requestor, exec.cancel)
 	if err != nil {
 		return base.PublicRoom{}, err
 	}
 
+	if exec.superseded() {
+		return base.PublicRoom{}, ErrSuperseded
+	}
+
 	//here's where we then pass that information through so that we can make a decent decision.
-	report, err := EvaluateResponses(responses, count)
+	report, err = EvaluateResponses(responses, count)
 	if err != nil {
 		return base.PublicRoom{}, err
 	}
 
 	report.Building = target.Building
 	report.Room = target.Room
+	report.Conflicts = conflicts
+
+	if VerifyEnabled() && !exec.superseded() {
+		report.Mismatches = verifyState(room, actions, target, requestor, exec.cancel)
+	}
+
+	rememberDesired(target, report)
 
 	color.Set(color.FgHiGreen, color.Bold)
 	log.L.Info("[state] successfully set room state")
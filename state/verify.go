@@ -0,0 +1,261 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/byuoitav/av-api/base"
+	se "github.com/byuoitav/av-api/statusevaluators"
+	ei "github.com/byuoitav/common/events"
+	"github.com/byuoitav/common/log"
+	"github.com/byuoitav/common/structs"
+	"github.com/fatih/color"
+)
+
+//DefaultVerifyDelay is how long to give devices to settle before reading their state back, if VERIFY_DELAY isn't set.
+const DefaultVerifyDelay = time.Second
+
+//VerifyEnabled reports whether VERIFY_STATE is set, so that state is read back after it is set.
+func VerifyEnabled() bool {
+	return strings.EqualFold(os.Getenv("VERIFY_STATE"), "true")
+}
+
+//verifyRetry reports whether VERIFY_RETRY is set, so that actions on a mismatched device are sent once more.
+func verifyRetry() bool {
+	return strings.EqualFold(os.Getenv("VERIFY_RETRY"), "true")
+}
+
+//verifyState reads back the state of each device the actions touched, using the status evaluators paired with them,
+//and returns the fields that don't match what the target asked for.
+//With VERIFY_RETRY, the actions on a mismatched device are sent again once, and the device is read back again.
+func verifyState(room structs.Room, actions []base.ActionStructure, target base.PublicRoom, requestor string, cancel <-chan struct{}) []base.Mismatch {
+
+	log.L.Infof("%s", color.HiBlueString("[state] verifying state of %s...", room.ID))
+
+	mismatches := compareState(room, actions, target)
+	if len(mismatches) == 0 || !verifyRetry() {
+		publishMismatches(room, mismatches, requestor)
+		return mismatches
+	}
+
+	//send the actions on each mismatched device again, reconciled into a DAG of their own so they run in the same order
+	var retries []base.ActionStructure
+	for _, action := range actions {
+		if action.Overridden {
+			continue
+		}
+
+		for _, mismatch := range mismatches {
+			if strings.EqualFold(mismatch.Device, action.DestinationDevice.Name) {
+				action.Children = nil
+				action.Join = nil
+				retries = append(retries, action)
+				break
+			}
+		}
+	}
+
+	log.L.Infof("%s", color.HiYellowString("[state] %v fields didn't match, retrying %v actions", len(mismatches), len(retries)))
+
+	dag, _, err := ReconcileActions(room, retries, len(retries))
+	if err != nil {
+		log.L.Errorf("%s", color.HiRedString("[state] unable to retry: %s", err.Error()))
+		publishMismatches(room, mismatches, requestor)
+		return mismatches
+	}
+
+	_, err = ExecuteActions(dag, requestor, cancel)
+	if err != nil {
+		log.L.Errorf("%s", color.HiRedString("[state] unable to retry: %s", err.Error()))
+	}
+
+	mismatches = compareState(room, retries, target)
+	publishMismatches(room, mismatches, requestor)
+
+	return mismatches
+}
+
+//compareState queries the devices the actions touched, and compares them to the target.
+func compareState(room structs.Room, actions []base.ActionStructure, target base.PublicRoom) []base.Mismatch {
+	delay, err := time.ParseDuration(os.Getenv("VERIFY_DELAY"))
+	if err != nil || delay < 0 {
+		delay = DefaultVerifyDelay
+	}
+	time.Sleep(delay)
+
+	touched := make(map[string]bool)
+	evaluators := make(map[string]bool)
+	for _, action := range actions {
+		if action.Overridden {
+			continue
+		}
+
+		touched[action.DestinationDevice.ID] = true
+		evaluators[statusEvaluatorFor(action.GeneratingEvaluator)] = true
+	}
+
+	//only the status evaluators paired with the actions are run, if the room has them
+	verifyRoom := room
+	verifyRoom.Configuration.Evaluators = nil
+	for _, evaluator := range room.Configuration.Evaluators {
+		if evaluators[evaluator.CodeKey] {
+			verifyRoom.Configuration.Evaluators = append(verifyRoom.Configuration.Evaluators, evaluator)
+		}
+	}
+
+	commands, _, err := GenerateStatusCommands(verifyRoom, se.StatusEvaluatorMap)
+	if err != nil {
+		log.L.Errorf("%s", color.HiRedString("[state] unable to verify state: %s", err.Error()))
+		return nil
+	}
+
+	var deviceCommands []se.StatusCommand
+	for _, command := range commands {
+		if touched[command.DestinationDevice.ID] {
+			deviceCommands = append(deviceCommands, command)
+		}
+	}
+
+	if len(deviceCommands) == 0 {
+		log.L.Infof("[state] nothing to verify")
+		return nil
+	}
+
+	responses, err := RunStatusCommands(deviceCommands)
+	if err != nil {
+		log.L.Errorf("%s", color.HiRedString("[state] unable to verify state: %s", err.Error()))
+		return nil
+	}
+
+	actual, err := EvaluateResponses(responses, len(deviceCommands))
+	if err != nil {
+		log.L.Errorf("%s", color.HiRedString("[state] unable to verify state: %s", err.Error()))
+		return nil
+	}
+
+	return compareTarget(target, actual)
+}
+
+//compareTarget lists the fields of each device in actual that don't match what target asked for, on the device itself or room-wide.
+func compareTarget(target, actual base.PublicRoom) []base.Mismatch {
+	var mismatches []base.Mismatch
+
+	for _, display := range actual.Displays {
+		desired := desiredDisplay(target, display.Name)
+		mismatches = compareField(mismatches, display.Name, "power", desired.Power, display.Power)
+		mismatches = compareField(mismatches, display.Name, "input", desired.Input, display.Input)
+		mismatches = compareField(mismatches, display.Name, "blanked", desired.Blanked, display.Blanked)
+	}
+
+	for _, audioDevice := range actual.AudioDevices {
+		desired := desiredAudioDevice(target, audioDevice.Name)
+		mismatches = compareField(mismatches, audioDevice.Name, "power", desired.Power, audioDevice.Power)
+		mismatches = compareField(mismatches, audioDevice.Name, "input", desired.Input, audioDevice.Input)
+		mismatches = compareField(mismatches, audioDevice.Name, "muted", desired.Muted, audioDevice.Muted)
+		mismatches = compareField(mismatches, audioDevice.Name, "volume", desired.Volume, audioDevice.Volume)
+	}
+
+	return mismatches
+}
+
+//desiredDisplay is what target asked of a display: what was asked of the display itself, or else room-wide.
+//Room-wide inputs are routed through switchers, so only an input asked for on the display itself is included.
+func desiredDisplay(target base.PublicRoom, name string) base.Display {
+	desired := base.Display{Device: base.Device{Name: name, Power: target.Power}, Blanked: target.Blanked}
+
+	for _, display := range target.Displays {
+		if !strings.EqualFold(display.Name, name) {
+			continue
+		}
+
+		if len(display.Power) > 0 {
+			desired.Power = display.Power
+		}
+		if display.Blanked != nil {
+			desired.Blanked = display.Blanked
+		}
+		desired.Input = display.Input
+	}
+
+	return desired
+}
+
+//desiredAudioDevice is what target asked of an audio device, like desiredDisplay.
+func desiredAudioDevice(target base.PublicRoom, name string) base.AudioDevice {
+	desired := base.AudioDevice{Device: base.Device{Name: name, Power: target.Power}, Muted: target.Muted, Volume: target.Volume}
+
+	for _, audioDevice := range target.AudioDevices {
+		if !strings.EqualFold(audioDevice.Name, name) {
+			continue
+		}
+
+		if len(audioDevice.Power) > 0 {
+			desired.Power = audioDevice.Power
+		}
+		if audioDevice.Muted != nil {
+			desired.Muted = audioDevice.Muted
+		}
+		if audioDevice.Volume != nil {
+			desired.Volume = audioDevice.Volume
+		}
+		desired.Input = audioDevice.Input
+	}
+
+	return desired
+}
+
+//compareField adds a mismatch if the desired value was set, and the device reported something else.
+func compareField(mismatches []base.Mismatch, device, field string, desired, actual interface{}) []base.Mismatch {
+	desiredString, set := fieldString(desired)
+	if !set {
+		return mismatches
+	}
+
+	//a field that couldn't be read is reported in the room's errors, not as a mismatch
+	actualString, read := fieldString(actual)
+	if !read || strings.EqualFold(desiredString, actualString) {
+		return mismatches
+	}
+
+	return append(mismatches, base.Mismatch{
+		Device:  device,
+		Field:   field,
+		Desired: desiredString,
+		Actual:  actualString,
+	})
+}
+
+//fieldString formats a status field, and reports whether it was set at all.
+func fieldString(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, len(v) > 0
+	case *bool:
+		if v == nil {
+			return "", false
+		}
+		return fmt.Sprintf("%v", *v), true
+	case *int:
+		if v == nil {
+			return "", false
+		}
+		return fmt.Sprintf("%v", *v), true
+	}
+
+	return "", false
+}
+
+func publishMismatches(room structs.Room, mismatches []base.Mismatch, requestor string) {
+	building, roomName := room.ID, ""
+	if split := strings.SplitN(room.ID, "-", 2); len(split) == 2 {
+		building, roomName = split[0], split[1]
+	}
+
+	for _, mismatch := range mismatches {
+		msg := fmt.Sprintf("%s reported %s %s after being set to %s", mismatch.Device, mismatch.Field, mismatch.Actual, mismatch.Desired)
+		log.L.Warnf("%s", color.HiYellowString("[state] %s", msg))
+		base.SendEvent(ei.ERROR, ei.INTERNAL, mismatch.Device, roomName, building, "verify-"+mismatch.Field, msg, requestor, true)
+	}
+}
@@ -0,0 +1,159 @@
+package state
+
+import (
+	"os"
+	"testing"
+
+	"github.com/byuoitav/av-api/base"
+)
+
+func TestCompareTarget(t *testing.T) {
+	yes, no := true, false
+	thirty, forty := 30, 40
+
+	tests := []struct {
+		name       string
+		target     base.PublicRoom
+		actual     base.PublicRoom
+		mismatches []base.Mismatch
+	}{
+		{
+			name:   "room-wide power",
+			target: base.PublicRoom{Power: "on"},
+			actual: base.PublicRoom{Displays: []base.Display{
+				{Device: base.Device{Name: "D1", Power: "on"}},
+				{Device: base.Device{Name: "D2", Power: "standby"}},
+			}},
+			mismatches: []base.Mismatch{{Device: "D2", Field: "power", Desired: "on", Actual: "standby"}},
+		},
+		{
+			name: "the device beats room-wide",
+			target: base.PublicRoom{
+				Power:    "on",
+				Displays: []base.Display{{Device: base.Device{Name: "d1", Power: "standby"}}},
+			},
+			actual: base.PublicRoom{Displays: []base.Display{
+				{Device: base.Device{Name: "D1", Power: "standby"}},
+				{Device: base.Device{Name: "D2", Power: "standby"}},
+			}},
+			mismatches: []base.Mismatch{{Device: "D2", Field: "power", Desired: "on", Actual: "standby"}},
+		},
+		{
+			name: "device inputs, but not room-wide ones",
+			target: base.PublicRoom{
+				CurrentVideoInput: "HDMI1",
+				Displays:          []base.Display{{Device: base.Device{Name: "D1", Input: "HDMI2"}}},
+			},
+			actual: base.PublicRoom{Displays: []base.Display{
+				{Device: base.Device{Name: "D1", Input: "HDMI1"}},
+				{Device: base.Device{Name: "D2", Input: "HDMI3"}},
+			}},
+			mismatches: []base.Mismatch{{Device: "D1", Field: "input", Desired: "HDMI2", Actual: "HDMI1"}},
+		},
+		{
+			name:   "audio",
+			target: base.PublicRoom{Muted: &no, AudioDevices: []base.AudioDevice{{Device: base.Device{Name: "MIC1"}, Volume: &thirty}}},
+			actual: base.PublicRoom{AudioDevices: []base.AudioDevice{
+				{Device: base.Device{Name: "MIC1"}, Muted: &yes, Volume: &forty},
+				{Device: base.Device{Name: "MIC2"}, Muted: &no, Volume: &forty},
+			}},
+			mismatches: []base.Mismatch{
+				{Device: "MIC1", Field: "muted", Desired: "false", Actual: "true"},
+				{Device: "MIC1", Field: "volume", Desired: "30", Actual: "40"},
+			},
+		},
+		{
+			name:   "fields that couldn't be read",
+			target: base.PublicRoom{Power: "on", Blanked: &no},
+			actual: base.PublicRoom{Displays: []base.Display{{Device: base.Device{Name: "D1"}}}},
+		},
+	}
+
+	for _, test := range tests {
+		mismatches := compareTarget(test.target, test.actual)
+		if len(mismatches) != len(test.mismatches) {
+			t.Errorf("%s: expected %+v, got %+v", test.name, test.mismatches, mismatches)
+			continue
+		}
+
+		for i := range mismatches {
+			if mismatches[i] != test.mismatches[i] {
+				t.Errorf("%s: expected %+v, got %+v", test.name, test.mismatches[i], mismatches[i])
+			}
+		}
+	}
+}
+
+func TestRememberDesired(t *testing.T) {
+	os.Setenv("LOCAL_ENVIRONMENT", "true")
+	os.Setenv("ENFORCE_INTERVAL", "30s")
+	defer os.Unsetenv("LOCAL_ENVIRONMENT")
+	defer os.Unsetenv("ENFORCE_INTERVAL")
+
+	yes := true
+	target := base.PublicRoom{
+		Building: "ITB",
+		Room:     "1101",
+		Power:    "on",
+		Displays: []base.Display{{Device: base.Device{Name: "D1", Input: "HDMI2"}, Blanked: &yes}},
+	}
+
+	//the report reflects what the devices acknowledged, which may not be what was asked for
+	report := base.PublicRoom{
+		Building: "ITB",
+		Room:     "1101",
+		Displays: []base.Display{
+			{Device: base.Device{Name: "D1", Power: "standby", Input: "HDMI1"}},
+			{Device: base.Device{Name: "D2", Power: "standby"}},
+			{Device: base.Device{Name: "D3"}},
+		},
+		Errors: []base.StatusError{{Device: "D3", Command: "PowerOn", Error: "non-200 response code: 500"}},
+	}
+
+	rememberDesired(target, report)
+
+	d := desired["ITB-1101"]
+	if d == nil {
+		t.Fatal("expected the room to be remembered")
+	}
+
+	if d1 := d.displays["D1"]; d1.Power != "on" || d1.Input != "HDMI2" || d1.Blanked == nil || !*d1.Blanked {
+		t.Errorf("expected D1 to be remembered as asked for, got %+v", d1)
+	}
+	if d2 := d.displays["D2"]; d2.Power != "on" || len(d2.Input) > 0 {
+		t.Errorf("expected D2 to be remembered with the room-wide power, got %+v", d2)
+	}
+	if d3, ok := d.displays["D3"]; ok {
+		t.Errorf("expected D3 not to be remembered, since its action failed, got %+v", d3)
+	}
+}
+
+func TestDriftChanges(t *testing.T) {
+	standby := base.Mismatch{Device: "D1", Field: "power", Desired: "on", Actual: "standby"}
+	hdmi1 := base.Mismatch{Device: "D1", Field: "input", Desired: "HDMI2", Actual: "HDMI1"}
+	hdmi3 := base.Mismatch{Device: "D1", Field: "input", Desired: "HDMI2", Actual: "HDMI3"}
+
+	checks := []struct {
+		drifted []base.Mismatch
+		changed []bool
+	}{
+		{[]base.Mismatch{standby}, []bool{true}},
+		{[]base.Mismatch{standby, hdmi1}, []bool{false, true}},
+		{[]base.Mismatch{standby, hdmi3}, []bool{false, true}},
+		{nil, nil},
+		{[]base.Mismatch{standby}, []bool{true}},
+	}
+
+	for i, check := range checks {
+		changed := driftChanges("ITB-1101", check.drifted)
+		if len(changed) != len(check.changed) {
+			t.Fatalf("check %v: expected %v, got %v", i, check.changed, changed)
+		}
+
+		for j := range changed {
+			if changed[j] != check.changed[j] {
+				t.Errorf("check %v: expected %v, got %v", i, check.changed, changed)
+			}
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package statusevaluators
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/byuoitav/av-api/base"
+	ei "github.com/byuoitav/common/events"
+	"github.com/byuoitav/common/log"
+	"github.com/byuoitav/common/structs"
+	"github.com/fatih/color"
+)
+
+// BatteryDefaultEvaluator is a constant variable for the name of the evaluator.
+const BatteryDefaultEvaluator = "STATUS_BatteryDefault"
+
+// BatteryDefaultCommand is a constant variable for the name of the command.
+const BatteryDefaultCommand = "STATUS_Battery"
+
+// DefaultLowBatteryThreshold is the battery percentage at or below which a mic is low, if LOW_BATTERY_THRESHOLD isn't set.
+const DefaultLowBatteryThreshold = 20
+
+// BatteryDefault implements the StatusEvaluator struct.
+type BatteryDefault struct {
+}
+
+// the last battery level we saw for each mic, so we only publish an event when it crosses the threshold
+var batteryLevels = make(map[string]int)
+var batteryMutex sync.Mutex
+
+// GetLowBatteryThreshold returns the threshold set in LOW_BATTERY_THRESHOLD, or DefaultLowBatteryThreshold.
+func GetLowBatteryThreshold() int {
+	threshold, err := strconv.Atoi(os.Getenv("LOW_BATTERY_THRESHOLD"))
+	if err != nil || threshold < 0 {
+		return DefaultLowBatteryThreshold
+	}
+
+	return threshold
+}
+
+// GetDevices returns a list of devices in the given room.
+//only wireless mics have batteries
+func (p *BatteryDefault) GetDevices(room structs.Room) ([]structs.Device, error) {
+	var mics []structs.Device
+	for _, device := range room.Devices {
+		if structs.HasRole(device, "Microphone") {
+			mics = append(mics, device)
+		}
+	}
+
+	return mics, nil
+}
+
+// GenerateCommands generates a list of commands for the given devices.
+func (p *BatteryDefault) GenerateCommands(devices []structs.Device) ([]StatusCommand, int, error) {
+
+	log.L.Info("[statusevals] Generating \"Battery\" status commands...")
+
+	var output []StatusCommand
+	var count int
+
+	for _, mic := range devices {
+		for _, command := range mic.Type.Commands {
+			if command.ID != BatteryDefaultCommand {
+				continue
+			}
+
+			log.L.Infof("[statusevals] Adding command: %s to action list with device %s", command.ID, mic.ID)
+
+			parameters := make(map[string]string)
+			parameters["address"] = mic.Address
+
+			//mics are reported with the audio devices
+			output = append(output, StatusCommand{
+				Action:            command,
+				Device:            mic,
+				Parameters:        parameters,
+				DestinationDevice: base.DestinationDevice{Device: mic, AudioDevice: true},
+				Generator:         BatteryDefaultEvaluator,
+			})
+			count++
+		}
+	}
+
+	return output, count, nil
+}
+
+// EvaluateResponse processes the response information that is given.
+func (p *BatteryDefault) EvaluateResponse(label string, value interface{}, source structs.Device, dest base.DestinationDevice) (string, interface{}, error) {
+	log.L.Infof("[statusevals] Evaluating response: %s, %v in evaluator %v", label, value, BatteryDefaultEvaluator)
+
+	if value == nil {
+		return label, value, errors.New("cannot process nil value")
+	}
+
+	switch label {
+	case "battery":
+		level, err := toInt(value)
+		if err != nil {
+			return label, value, fmt.Errorf("invalid battery level %v: %s", value, err.Error())
+		}
+
+		checkBatteryLevel(dest.Device, level)
+		return label, level, nil
+
+	case "rfSignal", "rf-signal", "rf_signal":
+		signal, err := toInt(value)
+		if err != nil {
+			return label, value, fmt.Errorf("invalid rf signal %v: %s", value, err.Error())
+		}
+
+		return "rfSignal", signal, nil
+
+	case "charging":
+		charging, ok := value.(bool)
+		if !ok {
+			return label, value, fmt.Errorf("invalid charging state %v", value)
+		}
+
+		return label, charging, nil
+	}
+
+	return label, value, nil
+}
+
+//checkBatteryLevel publishes an event when a mic's battery drops to the threshold, or recovers from it.
+func checkBatteryLevel(mic structs.Device, level int) {
+	threshold := GetLowBatteryThreshold()
+
+	batteryMutex.Lock()
+	previous, seen := batteryLevels[mic.ID]
+	batteryLevels[mic.ID] = level
+	batteryMutex.Unlock()
+
+	building, room := "", ""
+	if split := strings.Split(mic.ID, "-"); len(split) == 3 {
+		building, room = split[0], split[1]
+	}
+
+	switch {
+	case level <= threshold && (!seen || previous > threshold):
+		msg := fmt.Sprintf("%s battery is low: %v%%", mic.Name, level)
+		log.L.Warnf("%s", color.HiYellowString("[statusevals] %s", msg))
+		base.SendEvent(ei.DETAILSTATE, ei.ROOMSTATUSQUERY, mic.Name, room, building, "low-battery", strconv.Itoa(level), "", true)
+
+	case level > threshold && seen && previous <= threshold:
+		log.L.Infof("[statusevals] %s battery has recovered: %v%%", mic.Name, level)
+		base.SendEvent(ei.DETAILSTATE, ei.ROOMSTATUSQUERY, mic.Name, room, building, "battery", strconv.Itoa(level), "", false)
+	}
+}
+
+//toInt coerces a number from a status response, which JSON gives us as a float64, into an int.
+func toInt(value interface{}) (int, error) {
+	switch v := value.(type) {
+	case float64:
+		return int(v), nil
+	case int:
+		return v, nil
+	case string:
+		return strconv.Atoi(strings.TrimSuffix(v, "%"))
+	default:
+		return 0, fmt.Errorf("%v is not a number", value)
+	}
+}
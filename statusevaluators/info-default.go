@@ -0,0 +1,245 @@
+package statusevaluators
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/av-api/helpers"
+	"github.com/byuoitav/common/log"
+	"github.com/byuoitav/common/structs"
+)
+
+// InfoDefaultEvaluator is a constant variable for the name of the evaluator.
+const InfoDefaultEvaluator = "STATUS_InfoDefault"
+
+// InfoPrefix is put on the labels this evaluator returns, so they can be told apart from the standard status fields.
+const InfoPrefix = "info."
+
+// InfoField maps one field of a status command's response to a key in a device's info.
+// Field defaults to Key. Type is one of "string", "int", "float" or "bool"; an empty Type leaves the value as is.
+// Rooms ("ITB-1101") and DeviceTypes limit which devices the field is read from; empty lists match every device.
+type InfoField struct {
+	Command     string   `json:"command"`
+	Field       string   `json:"field,omitempty"`
+	Key         string   `json:"key"`
+	Type        string   `json:"type,omitempty"`
+	Rooms       []string `json:"rooms,omitempty"`
+	DeviceTypes []string `json:"deviceTypes,omitempty"`
+}
+
+// InfoDefault implements the StatusEvaluator struct.
+type InfoDefault struct {
+}
+
+var infoFields []InfoField
+var infoFieldsErr error
+var infoFieldsOnce sync.Once
+
+// GetInfoFields returns the fields configured in the file at STATUS_INFO_PATH. The file is optional:
+// without it, no info is read. A file that can't be parsed is an error.
+func GetInfoFields() ([]InfoField, error) {
+	infoFieldsOnce.Do(func() {
+		path := os.Getenv("STATUS_INFO_PATH")
+		if len(path) == 0 {
+			return
+		}
+
+		b, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			log.L.Infof("[statusevals] %s doesn't exist, no info fields will be read", path)
+			return
+		}
+		if err != nil {
+			infoFieldsErr = err
+			return
+		}
+
+		var config struct {
+			Fields []InfoField `json:"fields"`
+		}
+
+		err = json.Unmarshal(b, &config)
+		if err != nil {
+			infoFieldsErr = err
+			return
+		}
+
+		for i := range config.Fields {
+			if !strings.HasPrefix(config.Fields[i].Command, FLAG) {
+				infoFieldsErr = helpers.NewError(helpers.ConfigError, "%s is not a status command", config.Fields[i].Command)
+				return
+			}
+			if len(config.Fields[i].Field) == 0 {
+				config.Fields[i].Field = config.Fields[i].Key
+			}
+		}
+
+		infoFields = config.Fields
+		log.L.Infof("[statusevals] loaded %v info fields from %s", len(infoFields), path)
+	})
+
+	return infoFields, infoFieldsErr
+}
+
+// GetDevices returns a list of devices in the given room.
+func (p *InfoDefault) GetDevices(room structs.Room) ([]structs.Device, error) {
+	return room.Devices, nil
+}
+
+// GenerateCommands generates a list of commands for the given devices.
+//each configured command is only sent once per device, no matter how many fields it fills in.
+//the fields are read by a callback bound to the command, so a label is only matched against the fields of the command that returned it
+func (p *InfoDefault) GenerateCommands(devices []structs.Device) ([]StatusCommand, int, error) {
+	fields, err := GetInfoFields()
+	if err != nil {
+		//extra telemetry isn't worth failing the rest of the room's status over
+		log.L.Errorf("[statusevals] unable to load info fields: %s", err.Error())
+		return []StatusCommand{}, 0, nil
+	}
+
+	var output []StatusCommand
+	var count int
+
+	for _, device := range devices {
+		for _, command := range device.Type.Commands {
+			var wanted []InfoField
+			for _, field := range fields {
+				if field.Command == command.ID && field.appliesTo(device) {
+					wanted = append(wanted, field)
+				}
+			}
+
+			if len(wanted) == 0 {
+				continue
+			}
+
+			log.L.Infof("[statusevals] Adding command: %s to action list with device %s", command.ID, device.ID)
+
+			parameters := make(map[string]string)
+			parameters["address"] = device.Address
+
+			destinationDevice := base.DestinationDevice{
+				Device:      device,
+				AudioDevice: structs.HasRole(device, "AudioOut") || structs.HasRole(device, "Microphone"),
+				Display:     structs.HasRole(device, "VideoOut"),
+			}
+
+			output = append(output, StatusCommand{
+				Action:            command,
+				Device:            device,
+				Parameters:        parameters,
+				DestinationDevice: destinationDevice,
+				Generator:         InfoDefaultEvaluator,
+				Callback:          infoCallback(wanted),
+			})
+			count += len(wanted)
+		}
+	}
+
+	return output, count, nil
+}
+
+//appliesTo reports whether the field should be read from the device.
+func (f InfoField) appliesTo(device structs.Device) bool {
+	if len(f.DeviceTypes) > 0 && !containsFold(f.DeviceTypes, device.Type.ID) {
+		return false
+	}
+
+	if len(f.Rooms) > 0 {
+		parts := strings.Split(device.ID, "-")
+		if len(parts) < 3 || !containsFold(f.Rooms, parts[0]+"-"+parts[1]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//infoCallback turns the response to one command into info values, using only the fields configured for that command.
+//every field mapped to the label gets a value, so the same response field can be read under more than one key or type.
+func infoCallback(fields []InfoField) func(base.StatusPackage, chan<- base.StatusPackage) error {
+	return func(sp base.StatusPackage, c chan<- base.StatusPackage) error {
+		var err error
+
+		for _, field := range fields {
+			if field.Field != sp.Key {
+				continue
+			}
+
+			coerced, e := coerce(sp.Value, field.Type)
+			if e != nil {
+				log.L.Errorf("[statusevals] unable to read %s as %s: %s", field.Key, field.Type, e.Error())
+				err = e
+				continue
+			}
+
+			output := sp
+			output.Key = InfoPrefix + field.Key
+			output.Value = coerced
+			c <- output
+		}
+
+		return err
+	}
+}
+
+// EvaluateResponse processes the response information that is given. Info fields are read by the callback on
+// each command instead, since the label alone doesn't say which command it came from.
+func (p *InfoDefault) EvaluateResponse(label string, value interface{}, source structs.Device, dest base.DestinationDevice) (string, interface{}, error) {
+	return label, value, fmt.Errorf("%s should have been read by its command's callback", label)
+}
+
+//coerce converts a value from a status response into the configured type.
+func coerce(value interface{}, kind string) (interface{}, error) {
+	if value == nil {
+		return nil, errors.New("cannot process nil value")
+	}
+
+	switch kind {
+	case "":
+		return value, nil
+	case "string":
+		return fmt.Sprintf("%v", value), nil
+	case "int":
+		return toInt(value)
+	case "float":
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case int:
+			return float64(v), nil
+		case string:
+			return strconv.ParseFloat(v, 64)
+		}
+	case "bool":
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case float64:
+			return v != 0, nil
+		case string:
+			return strconv.ParseBool(v)
+		}
+	default:
+		return nil, fmt.Errorf("unknown type %s", kind)
+	}
+
+	return nil, fmt.Errorf("%v can't be converted to %s", value, kind)
+}
@@ -0,0 +1,31 @@
+package statusevaluators
+
+import (
+	"testing"
+
+	"github.com/byuoitav/av-api/base"
+)
+
+func TestInfoCallbackEmitsEveryMapping(t *testing.T) {
+	callback := infoCallback([]InfoField{
+		{Command: "STATUS_Info", Field: "hours", Key: "lampHours", Type: "int"},
+		{Command: "STATUS_Info", Field: "hours", Key: "lampHoursText", Type: "string"},
+		{Command: "STATUS_Info", Field: "model", Key: "model"},
+	})
+
+	c := make(chan base.StatusPackage, 3)
+	err := callback(base.StatusPackage{Key: "hours", Value: 1200.0}, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(c)
+
+	values := make(map[string]interface{})
+	for sp := range c {
+		values[sp.Key] = sp.Value
+	}
+
+	if len(values) != 2 || values[InfoPrefix+"lampHours"] != 1200 || values[InfoPrefix+"lampHoursText"] != "1200" {
+		t.Errorf("expected hours as both an int and a string, got %v", values)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/av-api/helpers"
 	"github.com/byuoitav/common/db"
 	"github.com/byuoitav/common/log"
 	"github.com/byuoitav/common/structs"
@@ -52,7 +53,7 @@ func (p *InputDSP) GenerateCommands(devices []structs.Device) ([]StatusCommand,
 
 	//validate DSP configuration
 	if dsps == nil || len(dsps) != 1 {
-		return []StatusCommand{}, 0, errors.New("Invalid DSP configuration detected")
+		return []StatusCommand{}, 0, helpers.NewError(helpers.ConfigError, "Invalid DSP configuration detected")
 	}
 
 	dsp := dsps[0]
@@ -68,7 +69,7 @@ func (p *InputDSP) GenerateCommands(devices []structs.Device) ([]StatusCommand,
 
 	//validate number of switchers
 	if switchers == nil || len(switchers) != 1 {
-		return []StatusCommand{}, 0, errors.New("[statusevals] Invalid video switcher configuration detected")
+		return []StatusCommand{}, 0, helpers.NewError(helpers.ConfigError, "[statusevals] Invalid video switcher configuration detected")
 	}
 
 	for _, port := range switchers[0].Ports {
@@ -0,0 +1,81 @@
+package statusevaluators
+
+import (
+	"errors"
+
+	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/common/log"
+	"github.com/byuoitav/common/structs"
+)
+
+// MotorizedDefaultEvaluator is a constant variable for the name of the evaluator.
+const MotorizedDefaultEvaluator = "STATUS_MotorizedDefault"
+
+// MotorizedDefaultCommand is a constant variable for the name of the command.
+const MotorizedDefaultCommand = "STATUS_Motorized"
+
+// MotorizedDefault implements the StatusEvaluator struct for screens, lifts, and shades.
+type MotorizedDefault struct {
+}
+
+// GetDevices returns a list of devices in the given room.
+func (p *MotorizedDefault) GetDevices(room structs.Room) ([]structs.Device, error) {
+	var devices []structs.Device
+	for _, device := range room.Devices {
+		if structs.HasRole(device, base.ScreenRole) || structs.HasRole(device, base.LiftRole) || structs.HasRole(device, base.ShadeRole) {
+			devices = append(devices, device)
+		}
+	}
+
+	return devices, nil
+}
+
+// GenerateCommands generates a list of commands for the given devices.
+func (p *MotorizedDefault) GenerateCommands(devices []structs.Device) ([]StatusCommand, int, error) {
+
+	log.L.Info("[statusevals] Generating \"Motorized\" status commands...")
+
+	var output []StatusCommand
+	for _, device := range devices {
+		for _, command := range device.Type.Commands {
+			if command.ID != MotorizedDefaultCommand {
+				continue
+			}
+
+			log.L.Infof("[statusevals] Adding command: %s to action list with device %s", command.ID, device.ID)
+
+			parameters := make(map[string]string)
+			parameters["address"] = device.Address
+
+			output = append(output, StatusCommand{
+				Action:            command,
+				Device:            device,
+				Parameters:        parameters,
+				DestinationDevice: base.DestinationDevice{Device: device, Motorized: true},
+				Generator:         MotorizedDefaultEvaluator,
+			})
+		}
+	}
+
+	return output, len(output), nil
+}
+
+// EvaluateResponse processes the response information that is given.
+func (p *MotorizedDefault) EvaluateResponse(label string, value interface{}, source structs.Device, dest base.DestinationDevice) (string, interface{}, error) {
+	log.L.Infof("[statusevals] Evaluating response: %s, %v in evaluator %v", label, value, MotorizedDefaultEvaluator)
+
+	if value == nil {
+		return label, value, errors.New("cannot process nil value")
+	}
+
+	if label == "position" {
+		position, err := toInt(value)
+		if err != nil {
+			return label, value, err
+		}
+
+		return label, position, nil
+	}
+
+	return label, value, nil
+}
@@ -5,6 +5,7 @@ import (
 	"strconv"
 
 	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/av-api/helpers"
 	"github.com/byuoitav/common/db"
 	"github.com/byuoitav/common/log"
 	"github.com/byuoitav/common/structs"
@@ -115,7 +116,7 @@ func generateMicStatusCommands(mics []structs.Device, evaluator string, command
 
 	if len(dsp) != 1 {
 		errorMessage := "[statusevals] Invalid number of DSP devices found in room: " + strconv.Itoa(len(dsp))
-		return []StatusCommand{}, 0, errors.New(errorMessage)
+		return []StatusCommand{}, 0, helpers.NewError(helpers.ConfigError, "%s", errorMessage)
 	}
 
 	var count int
@@ -165,7 +166,7 @@ func generateDSPStatusCommands(dsp []structs.Device, evaluator string, command s
 	//validate the correct number of dsps
 	if dsp == nil || len(dsp) != 1 {
 		errorMessage := "[statusevals] Invalid number of DSP devices found in room: " + strconv.Itoa(len(dsp))
-		return []StatusCommand{}, 0, errors.New(errorMessage)
+		return []StatusCommand{}, 0, helpers.NewError(helpers.ConfigError, "%s", errorMessage)
 	}
 
 	log.L.Infof("[statusevals] Generating DSP status command: %s against device: %s", command, dsp[0])
@@ -57,6 +57,7 @@ type StatusResponse struct {
 	DestinationDevice base.DestinationDevice `json:"destination_device"`
 	Callback          func(base.StatusPackage, chan<- base.StatusPackage) error
 	Generator         string                 `json:"generator"`
+	Action            string                 `json:"action,omitempty"`
 	Status            map[string]interface{} `json:"status"`
 	ErrorMessage      *string                `json:"error"`
 }
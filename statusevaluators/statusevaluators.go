@@ -4,6 +4,7 @@ import (
 	"strings"
 
 	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/av-api/registry"
 	"github.com/byuoitav/common/log"
 	"github.com/byuoitav/common/structs"
 )
@@ -21,19 +22,36 @@ type StatusEvaluator interface {
 	EvaluateResponse(label string, value interface{}, Source structs.Device, Destination base.DestinationDevice) (string, interface{}, error)
 }
 
-// StatusEvaluatorMap is a map of the different StatusEvaluators used.
-//TODO: we shoud grab the keys from constants in the evaluators themselves
-var StatusEvaluatorMap = map[string]StatusEvaluator{
-	"STATUS_PowerDefault":       &PowerDefault{},
-	"STATUS_BlankedDefault":     &BlankedDefault{},
-	"STATUS_MutedDefault":       &MutedDefault{},
-	"STATUS_InputDefault":       &InputDefault{},
-	"STATUS_VolumeDefault":      &VolumeDefault{},
-	"STATUS_InputVideoSwitcher": &InputVideoSwitcher{},
-	"STATUS_InputDSP":           &InputDSP{},
-	"STATUS_MutedDSP":           &MutedDSP{},
-	"STATUS_VolumeDSP":          &VolumeDSP{},
-	"STATUS_Tiered_Switching":   &InputTieredSwitcher{},
+// StatusEvaluatorMap is a map of the different StatusEvaluators used, filled in by Register.
+var StatusEvaluatorMap = make(map[string]StatusEvaluator)
+
+// Register adds a status evaluator under key. The registry reports a key that is registered twice.
+func Register(key string, evaluator StatusEvaluator) {
+	registry.Register(registry.Entry{
+		Key:   key,
+		Kind:  registry.Status,
+		Value: evaluator,
+	})
+
+	if _, ok := StatusEvaluatorMap[key]; !ok {
+		StatusEvaluatorMap[key] = evaluator
+	}
+}
+
+func init() {
+	Register(PowerDefaultEvaluator, &PowerDefault{})
+	Register(BlankedDefaultEvaluator, &BlankedDefault{})
+	Register(MutedDefaultEvaluator, &MutedDefault{})
+	Register(DefaultInputEvaluator, &InputDefault{})
+	Register(VolumeDefaultEvaluator, &VolumeDefault{})
+	Register(InputVideoSwitcherEvaluator, &InputVideoSwitcher{})
+	Register(InputDSPEvaluator, &InputDSP{})
+	Register(MutedDSPEvaluator, &MutedDSP{})
+	Register(VolumeDSPEvaluator, &VolumeDSP{})
+	Register(InputTieredSwitcherEvaluator, &InputTieredSwitcher{})
+	Register(BatteryDefaultEvaluator, &BatteryDefault{})
+	Register(InfoDefaultEvaluator, &InfoDefault{})
+	Register(MotorizedDefaultEvaluator, &MotorizedDefault{})
 }
 
 func generateStandardStatusCommand(devices []structs.Device, evaluatorName string, commandName string) ([]StatusCommand, int, error) {
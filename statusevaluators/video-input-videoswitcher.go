@@ -1,11 +1,11 @@
 package statusevaluators
 
 import (
-	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/byuoitav/av-api/base"
+	"github.com/byuoitav/av-api/helpers"
 	"github.com/byuoitav/common/db"
 	"github.com/byuoitav/common/log"
 	"github.com/byuoitav/common/structs"
@@ -141,7 +141,7 @@ func (p *InputVideoSwitcher) EvaluateResponse(label string, value interface{}, s
 	if len(switcherList) != 1 {
 		msg := fmt.Sprintf("[statusevals] Invalid room for this evaluator, there are %v switchers in the room, expecting 1", len(switcherList))
 		log.L.Error(msg)
-		return "", nil, errors.New(msg)
+		return "", nil, helpers.NewError(helpers.ConfigError, "%s", msg)
 	}
 
 	//source and dest are in the value string
@@ -149,7 +149,7 @@ func (p *InputVideoSwitcher) EvaluateResponse(label string, value interface{}, s
 	if !ok {
 		errString := "[statusevals] Invalid response value for this evaluiator, expects a string"
 		log.L.Error(errString)
-		return "", nil, errors.New(errString)
+		return "", nil, helpers.NewError(helpers.DeviceRejected, "%s", errString)
 	}
 
 	for _, port := range switcherList[0].Ports {